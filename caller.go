@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"runtime"
+	"strconv"
+)
+
+// количество кадров стека между пользовательским кодом и методом log:
+// log -> Info (или другой публичный метод) -> код пользователя
+const logCallerSkip = 2
+
+// место в коде, откуда была сделана запись
+type frameType struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+func (f frameType) String() string {
+	return f.File + ":" + strconv.Itoa(f.Line)
+}
+
+// возвращает кадр стека на skip уровней выше вызывающей функции
+func getCaller(skip int) *frameType {
+	pcs := make([]uintptr, 1)
+	if runtime.Callers(skip+2, pcs) == 0 {
+		return nil
+	}
+
+	return frameFromPC(pcs[0])
+}
+
+func frameFromPC(pc uintptr) *frameType {
+	if pc == 0 {
+		return nil
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.File == "" {
+		return nil
+	}
+
+	return &frameType{Function: frame.Function, File: frame.File, Line: frame.Line}
+}
+
+// интерфейс логгеров, которые умеют сдвигать место вызова
+type callerSkipper interface {
+	withCallerSkip(skip int) ILogger
+}
+
+// AddCallerSkip возвращает логгер, который пропускает еще skip кадров стека
+// при определении места вызова. нужен для оберток над логгером:
+// если функция-обертка вызывает logger.Info, в запись должна попасть не она, а ее вызывающий код
+func AddCallerSkip(l ILogger, skip int) ILogger {
+	if skipper, ok := l.(callerSkipper); ok {
+		return skipper.withCallerSkip(skip)
+	}
+
+	return l
+}
+
+func (l *logger) withCallerSkip(skip int) ILogger {
+	return &logger{
+		core:       l.core,
+		fields:     l.fields,
+		callerSkip: l.callerSkip + skip,
+	}
+}
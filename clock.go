@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock источник времени логгера: время записей, имена файлов по дням и таймеры горутин.
+// по умолчанию используется системное время, в тестах - clocktest.Clock
+type Clock interface {
+	Now() time.Time
+	//AfterFunc вызывает f через d и возвращает функцию отмены, как time.AfterFunc(d, f).Stop
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
+
+// timerType повторно используемый таймер поверх Clock.AfterFunc.
+// после Stop и Reset в канале не остается старых срабатываний
+type timerType struct {
+	clock Clock
+	c     chan time.Time
+
+	mu   sync.Mutex
+	gen  uint64 //поколение таймера, срабатывания прошлых поколений игнорируются
+	stop func() bool
+}
+
+func newTimer(clock Clock) *timerType {
+	return &timerType{clock: clock, c: make(chan time.Time, 1)}
+}
+
+// Reset взводит таймер на d, отменяя предыдущий запуск
+func (t *timerType) Reset(d time.Duration) {
+	t.Stop()
+
+	t.mu.Lock()
+	gen := t.gen
+	t.mu.Unlock()
+
+	stop := t.clock.AfterFunc(d, func() { t.fire(gen) })
+
+	t.mu.Lock()
+	t.stop = stop
+	t.mu.Unlock()
+}
+
+// Stop останавливает таймер и убирает из канала несчитанное срабатывание
+func (t *timerType) Stop() {
+	t.mu.Lock()
+	t.gen++
+	stop := t.stop
+	t.stop = nil
+
+	select {
+	case <-t.c:
+	default:
+	}
+	t.mu.Unlock()
+
+	//отмена вне блокировки, часы могут в этот момент вызывать fire
+	if stop != nil {
+		stop()
+	}
+}
+
+func (t *timerType) fire(gen uint64) {
+	now := t.clock.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if gen != t.gen {
+		return
+	}
+
+	select {
+	case t.c <- now:
+	default:
+	}
+}
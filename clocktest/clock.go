@@ -0,0 +1,121 @@
+// Package clocktest фейковые часы для детерминированных тестов логгера.
+// время двигается только через Advance и Set, таймеры срабатывают в момент перевода часов
+//
+//	clock := clocktest.NewClock(time.Date(2024, 1, 1, 23, 59, 0, 0, time.Local))
+//	l := logger.New(&logger.LoggerConf{..., Clock: clock})
+//	l.Info("до полуночи", nil)
+//	clock.Advance(time.Minute)
+package clocktest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock фейковые часы, реализуют logger.Clock
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+// отложенный вызов AfterFunc
+type waiter struct {
+	deadline time.Time
+	f        func()
+}
+
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// AfterFunc вызовет f, когда часы будут переведены на d вперед или дальше.
+// возвращает функцию отмены с семантикой time.Timer.Stop
+func (c *Clock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := &waiter{deadline: c.now.Add(d), f: f}
+	c.waiters = append(c.waiters, w)
+
+	return func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		for i, other := range c.waiters {
+			if other == w {
+				c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// Advance переводит часы вперед на d и вызывает наступившие AfterFunc
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.set(c.now.Add(d))
+}
+
+// Set переводит часы на t и вызывает наступившие AfterFunc
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	c.set(t)
+}
+
+// вызывается под блокировкой, снимает ее перед вызовом функций,
+// потому что они могут обращаться к часам
+func (c *Clock) set(t time.Time) {
+	c.now = t
+
+	var due []func()
+	pending := c.waiters[:0]
+
+	for _, w := range c.waiters {
+		if w.deadline.After(t) {
+			pending = append(pending, w)
+		} else {
+			due = append(due, w.f)
+		}
+	}
+
+	c.waiters = pending
+	c.mu.Unlock()
+
+	for _, f := range due {
+		f()
+	}
+}
+
+// Waiters количество ожидающих AfterFunc
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.waiters)
+}
+
+// BlockUntil ждет, пока количество ожидающих AfterFunc не станет равно n,
+// но не дольше timeout реального времени. позволяет дождаться,
+// что горутина логгера взвела или остановила таймер
+func (c *Clock) BlockUntil(n int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
+	for c.Waiters() != n {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	return true
+}
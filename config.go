@@ -1,5 +1,11 @@
 package logger
 
+import (
+	"io"
+	"sync"
+	"time"
+)
+
 type LoggerConf struct {
 	//уровни, которые напечатаются в консоль
 	PrintInfo  bool `yaml:"PrintInfo" env:"LOGGER_PRINT_INFO"`
@@ -18,13 +24,52 @@ type LoggerConf struct {
 	Color          bool   `yaml:"Color" env:"LOGGER_COLOR"`                    //раскрасить уровень лога для лучшей визуализации в консоли
 	DebugLog       bool   `yaml:"DebugLog" env:"LOGGER_DEBUG_LOG"`             //дебаг логи самого логгера
 	PathFolder     string `yaml:"PathFolder" env:"LOGGER_PATH_FOLDER"`         //папка для сохранения логов
+	AfterStop      string `yaml:"AfterStop" env:"LOGGER_AFTER_STOP"`           //куда деваются записи после остановки: stderr (по умолчанию) или drop
+	Durable        bool   `yaml:"Durable" env:"LOGGER_DURABLE"`                //журнал упреждающей записи с fsync, записи переживают падение процесса
+
+	FlushInterval time.Duration `yaml:"FlushInterval" env:"LOGGER_FLUSH_INTERVAL"`  //то же, что WriteTimout, но с точностью до наносекунд, например 200ms. если задан, WriteTimout не используется
+	MaxBatchBytes int           `yaml:"MaxBatchBytes" env:"LOGGER_MAX_BATCH_BYTES"` //пачка пишется в файл, как только ее размер в байтах достигнет этого значения. 0 - без ограничения
+
+	FlushOn     string `yaml:"FlushOn" env:"LOGGER_FLUSH_ON"`          //уровень, начиная с которого запись сразу пишет пачку в файл, например error (тогда и fatal, panic)
+	FlushOnWait bool   `yaml:"FlushOnWait" env:"LOGGER_FLUSH_ON_WAIT"` //вызов логгера для таких записей ждет записи в файл и fsync
+
+	//политики при заполненном канале уровня: block (по умолчанию), timeout, drop_newest, drop_oldest, spill
+	OverflowInfo    string        `yaml:"OverflowInfo" env:"LOGGER_OVERFLOW_INFO"`
+	OverflowError   string        `yaml:"OverflowError" env:"LOGGER_OVERFLOW_ERROR"`
+	OverflowDebug   string        `yaml:"OverflowDebug" env:"LOGGER_OVERFLOW_DEBUG"`
+	OverflowTimeout time.Duration `yaml:"OverflowTimeout" env:"LOGGER_OVERFLOW_TIMEOUT"` //ожидание места в канале для политики timeout, по умолчанию 1s
+
+	//шаблоны в синтаксисе text/template, например "{{.Time}} [{{.Level}}] {{.Message}} {{.Params}} {{.Error}}"
+	//если шаблон не задан, используется формат по умолчанию
+	FileTemplate    string `yaml:"FileTemplate" env:"LOGGER_FILE_TEMPLATE"`       //шаблон строки в файле, применяется к формату text
+	ConsoleTemplate string `yaml:"ConsoleTemplate" env:"LOGGER_CONSOLE_TEMPLATE"` //шаблон строки в консоли
+
+	AddCaller  bool `yaml:"AddCaller" env:"LOGGER_ADD_CALLER"`   //записывать файл, строку и функцию, откуда был вызван логгер
+	CallerSkip int  `yaml:"CallerSkip" env:"LOGGER_CALLER_SKIP"` //сколько кадров стека пропустить, если логгер вызывается через обертку
+
+	StackTrace      bool `yaml:"StackTrace" env:"LOGGER_STACK_TRACE"`            //записывать стек вызовов для уровня error и для ошибок с методом StackTrace()
+	StackFrameLimit int  `yaml:"StackFrameLimit" env:"LOGGER_STACK_FRAME_LIMIT"` //максимальное количество кадров в стеке, по умолчанию 32
+
+	Output io.Writer `yaml:"-" env:"-"` //куда печатаются уровни Print*, по умолчанию os.Stdout
+	Hook   Hook      `yaml:"-" env:"-"` //получает каждую запись, например для logtest
+	Clock  Clock     `yaml:"-" env:"-"` //источник времени, по умолчанию системный. в тестах clocktest.Clock
+
+	//функции, которые достают поля из контекста в методах *Ctx и WithContext
+	//если не заданы, используются DefaultContextExtractors
+	ContextExtractors []ContextExtractor `yaml:"-" env:"-"`
 }
 
-var loggerConfig *LoggerConf
+var (
+	loggerConfig     *LoggerConf
+	loggerConfigOnce sync.Once
+)
 
+// GetConfig общий для процесса конфиг.
+//
+// Deprecated: конфиг у каждого логгера свой, передавайте его или опции в New
 func GetConfig() *LoggerConf {
-	if loggerConfig == nil { //если данные есть в переменной, то возвращаем ее
+	loggerConfigOnce.Do(func() {
 		loggerConfig = &LoggerConf{}
-	}
+	})
 	return loggerConfig
 }
@@ -2,9 +2,14 @@ package logger
 
 // levels
 const (
-	Info  = "info"
-	Debug = "debug"
-	Error = "error"
+	InfoLevel  = "info"
+	DebugLevel = "debug"
+	ErrorLevel = "error"
+
+	//записываются вместе с error в его файл, после записи логгер
+	//сбрасывает буферы всех уровней и завершает программу или паникует
+	FatalLevel = "fatal"
+	PanicLevel = "panic"
 )
 
 const (
@@ -12,6 +17,21 @@ const (
 	TextFormat = "text"
 )
 
+// политики поведения при заполненном канале уровня
+const (
+	OverflowBlock        = "block"       //ждать места в канале (по умолчанию)
+	OverflowBlockTimeout = "timeout"     //ждать не дольше OverflowTimeout, потом отбросить запись
+	OverflowDropNewest   = "drop_newest" //отбросить новую запись
+	OverflowDropOldest   = "drop_oldest" //отбросить самую старую запись из канала
+	OverflowSpill        = "spill"       //положить в неограниченную очередь переполнения
+)
+
+// что делать с записями, сделанными после остановки логгера
+const (
+	AfterStopStderr = "stderr" //писать в stderr
+	AfterStopDrop   = "drop"   //отбрасывать, количество доступно через Dropped()
+)
+
 //Цвет	Основной	Фон
 //Стандартный	\033[39m	\033[49m
 //Чёрный	\033[30m	\033[40m
@@ -0,0 +1,83 @@
+package logger
+
+import "context"
+
+// ContextExtractor достает из контекста поля для записи, например trace id или request id
+type ContextExtractor func(ctx context.Context) []Field
+
+type contextKey string
+
+const (
+	traceIDKey   contextKey = "trace_id"
+	spanIDKey    contextKey = "span_id"
+	requestIDKey contextKey = "request_id"
+	userIDKey    contextKey = "user_id"
+)
+
+// экстракторы по умолчанию, читают значения, положенные в контекст функциями ContextWith*
+var DefaultContextExtractors = []ContextExtractor{
+	ExtractValue(string(traceIDKey), traceIDKey),
+	ExtractValue(string(spanIDKey), spanIDKey),
+	ExtractValue(string(requestIDKey), requestIDKey),
+	ExtractValue(string(userIDKey), userIDKey),
+}
+
+func ContextWithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey, id)
+}
+
+func ContextWithSpanID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, spanIDKey, id)
+}
+
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+func ContextWithUserID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, userIDKey, id)
+}
+
+// ExtractValue экстрактор, который берет из контекста значение по ключу key
+// и записывает его в поле name. если значения нет, поле не добавляется
+func ExtractValue(name string, key interface{}) ContextExtractor {
+	return func(ctx context.Context) []Field {
+		value := ctx.Value(key)
+		if value == nil {
+			return nil
+		}
+
+		return []Field{Any(name, value)}
+	}
+}
+
+// собирает поля из контекста всеми зарегистрированными экстракторами
+func (l *logger) contextFields(ctx context.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+
+	var fields []Field
+	for _, extract := range l.extractors {
+		fields = append(fields, extract(ctx)...)
+	}
+
+	return fields
+}
+
+// WithContext возвращает дочерний логгер с полями, извлеченными из ctx
+func (l *logger) WithContext(ctx context.Context) ILogger {
+	return l.With(l.contextFields(ctx)...)
+}
+
+func (l *logger) InfoCtx(ctx context.Context, msg string, err error, params ...string) {
+	l.log(ctx, InfoLevel, msg, err, params, nil)
+}
+
+func (l *logger) DebugCtx(ctx context.Context, msg string, err error, params ...string) {
+	l.log(ctx, DebugLevel, msg, err, params, nil)
+}
+
+func (l *logger) ErrorCtx(ctx context.Context, msg string, err error, params ...string) {
+	l.log(ctx, ErrorLevel, msg, err, params, nil)
+}
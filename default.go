@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// логгер по умолчанию вместе с его копией для пакетных функций,
+// которая пропускает лишний кадр стека, чтобы место вызова указывало на вызывающий код
+type defaultLogger struct {
+	logger  ILogger
+	wrapped ILogger
+}
+
+var defaultHolder atomic.Pointer[defaultLogger]
+
+func init() {
+	SetDefault(newStderrLogger())
+}
+
+// логгер по умолчанию только печатает в stderr. горутин он не запускает,
+// поэтому импорт пакета не оставляет в программе горутин, которые никто не остановит
+func newStderrLogger() *logger {
+	return New(&LoggerConf{
+		PrintInfo:  true,
+		PrintError: true,
+		Output:     os.Stderr,
+	})
+}
+
+// SetDefault заменяет логгер, которому передают вызовы функции пакета Info, Debug и Error.
+// безопасно вызывать одновременно с логированием. предыдущий логгер не останавливается
+func SetDefault(l ILogger) {
+	if l == nil {
+		l = Nop()
+	}
+
+	defaultHolder.Store(&defaultLogger{logger: l, wrapped: AddCallerSkip(l, 1)})
+}
+
+// Default возвращает текущий логгер по умолчанию.
+// изначально это логгер, который печатает info и error в stderr и ничего не пишет в файлы
+func Default() ILogger {
+	return defaultHolder.Load().logger
+}
+
+// Info пишет запись уровня info через логгер по умолчанию
+func Info(msg string, err error, params ...string) {
+	defaultHolder.Load().wrapped.Info(msg, err, params...)
+}
+
+// Debug пишет запись уровня debug через логгер по умолчанию
+func Debug(msg string, err error, params ...string) {
+	defaultHolder.Load().wrapped.Debug(msg, err, params...)
+}
+
+// Error пишет запись уровня error через логгер по умолчанию
+func Error(msg string, err error, params ...string) {
+	defaultHolder.Load().wrapped.Error(msg, err, params...)
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorFielder ошибка, которая добавляет свои поля в запись, например код ответа или id сущности
+type ErrorFielder interface {
+	LogFields() []Field
+}
+
+// максимальная глубина разбора цепочки ошибок, защита от зацикленных Unwrap
+const maxErrorDepth = 32
+
+// структура ошибки в записи
+type errorInfo struct {
+	Message string       `json:"message"`
+	Type    string       `json:"type"`
+	Chain   []errorLink  `json:"chain,omitempty"`  //ошибки, обернутые через %w, от внешней к внутренней
+	Causes  []*errorInfo `json:"causes,omitempty"` //ошибки, объединенные через errors.Join или Unwrap() []error
+	Fields  fieldList    `json:"fields,omitempty"` //поля от ошибок цепочки с интерфейсом ErrorFielder
+}
+
+// звено цепочки обернутых ошибок
+type errorLink struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+func newErrorInfo(err error) *errorInfo {
+	return buildErrorInfo(err, 0)
+}
+
+// разбирает цепочку errors.Unwrap до первой составной ошибки,
+// причины составной ошибки разбираются рекурсивно
+func buildErrorInfo(err error, depth int) *errorInfo {
+	info := &errorInfo{
+		Message: err.Error(),
+		Type:    errorTypeName(err),
+	}
+
+	for current := err; current != nil && depth < maxErrorDepth; depth++ {
+		if fielder, ok := current.(ErrorFielder); ok {
+			//поля внешней ошибки важнее полей внутренней
+			info.Fields = mergeFields(fieldList(fielder.LogFields()), info.Fields)
+		}
+
+		if multi, ok := current.(interface{ Unwrap() []error }); ok {
+			for _, cause := range multi.Unwrap() {
+				if cause != nil {
+					info.Causes = append(info.Causes, buildErrorInfo(cause, depth+1))
+				}
+			}
+			break
+		}
+
+		current = errors.Unwrap(current)
+		if current != nil {
+			info.Chain = append(info.Chain, errorLink{Message: current.Error(), Type: errorTypeName(current)})
+		}
+	}
+
+	return info
+}
+
+func errorTypeName(err error) string {
+	return fmt.Sprintf("%T", err)
+}
@@ -0,0 +1,206 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Field типизированный параметр записи. в json попадает как поле объекта fields
+// с сохранением типа значения (число, булево, строка), а не как строка key=value
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key string, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Float(key string, value float64) Field {
+	return Field{Key: key, Value: value}
+}
+
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+// в json записывается количеством наносекунд, в текст - строкой вида 1.5s
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value}
+}
+
+// в json и текст записывается в формате RFC3339Nano
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Value: value}
+}
+
+// записывает текст ошибки
+func Err(key string, err error) Field {
+	return Field{Key: key, Value: err}
+}
+
+// произвольное значение. в json записывается через json.Marshal
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// LogValuer значение поля, которое вычисляется только если запись действительно
+// попадет в консоль или в файл. подходит для дорогих в подготовке значений
+type LogValuer interface {
+	LogValue() interface{}
+}
+
+// функция как LogValuer
+type lazyValue func() interface{}
+
+func (f lazyValue) LogValue() interface{} {
+	return f()
+}
+
+// поле, значение которого вычисляется функцией fn только для включенных уровней
+func Lazy(key string, fn func() interface{}) Field {
+	return Field{Key: key, Value: lazyValue(fn)}
+}
+
+// вычисляет значения LogValuer. исходный список не меняется,
+// так как может принадлежать родительскому логгеру
+func resolveFields(fields fieldList) fieldList {
+	var resolved fieldList
+
+	for i, f := range fields {
+		valuer, ok := f.Value.(LogValuer)
+		if !ok {
+			continue
+		}
+
+		if resolved == nil {
+			resolved = make(fieldList, len(fields))
+			copy(resolved, fields)
+		}
+
+		resolved[i] = Any(f.Key, valuer.LogValue())
+	}
+
+	if resolved == nil {
+		return fields
+	}
+
+	return resolved
+}
+
+// строковое представление значения для текстового формата и консоли
+func (f Field) valueString() string {
+	switch v := f.Value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case error:
+		if v == nil {
+			return "<nil>"
+		}
+		return v.Error()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (f Field) String() string {
+	return f.Key + "=" + f.valueString()
+}
+
+func (f Field) marshalValue() []byte {
+	var value interface{}
+
+	switch v := f.Value.(type) {
+	case time.Duration:
+		value = int64(v)
+	case time.Time:
+		value = v.Format(time.RFC3339Nano)
+	case error:
+		value = f.valueString()
+	case float64:
+		//json не умеет NaN и бесконечность
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			value = strconv.FormatFloat(v, 'g', -1, 64)
+		} else {
+			value = v
+		}
+	default:
+		value = v
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(f.Value))
+	}
+
+	return data
+}
+
+// список полей, который сериализуется в json объект с сохранением порядка ключей
+type fieldList []Field
+
+func (list fieldList) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+	for i, f := range list {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(f.Key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(f.marshalValue())
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// объединяет списки полей в новый список. поле из extra заменяет одноименное поле из base
+func mergeFields(base fieldList, extra fieldList) fieldList {
+	if len(extra) == 0 {
+		return base
+	}
+
+	//extra может быть срезом вызывающего, который он потом изменит
+	if len(base) == 0 {
+		return append(fieldList(nil), extra...)
+	}
+
+	merged := make(fieldList, len(base), len(base)+len(extra))
+	copy(merged, base)
+
+	for _, f := range extra {
+		replaced := false
+
+		for i := range merged {
+			if merged[i].Key == f.Key {
+				merged[i] = f
+				replaced = true
+				break
+			}
+		}
+
+		if !replaced {
+			merged = append(merged, f)
+		}
+	}
+
+	return merged
+}
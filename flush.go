@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"path"
+)
+
+// запрос горутине уровня на запись слайса и всего, что уже лежит в канале
+type flushRequest struct {
+	sync bool       //после записи сделать fsync файла
+	done chan error //результат записи, буферизированный, чтобы горутина не ждала ушедшего вызывающего
+}
+
+// Flush просит горутины всех уровней записать накопленные логи, в том числе из каналов,
+// и ждет подтверждения или завершения ctx. в отличие от Stop логгер продолжает работать
+func (l *logger) Flush(ctx context.Context) error {
+	return l.flush(ctx, false, InfoLevel, DebugLevel, ErrorLevel)
+}
+
+// Sync то же самое, что Flush, но дополнительно делает fsync файлов текущего дня
+func (l *logger) Sync() error {
+	return l.flush(context.Background(), true, InfoLevel, DebugLevel, ErrorLevel)
+}
+
+// сбрасывает все буферы, ошибки записи уже выведены в stderr
+func (l *logger) flushAll() {
+	l.flush(context.Background(), false, InfoLevel, DebugLevel, ErrorLevel)
+}
+
+// сбрасывает буфер горутины одного уровня
+func (l *logger) flushLevel(level string) {
+	l.flush(context.Background(), false, level)
+}
+
+func (l *logger) flush(ctx context.Context, sync bool, levels ...string) error {
+	//сначала отправляю запросы всем горутинам, чтобы они писали параллельно, потом жду ответы.
+	//запросы отправляются под RLock, чтобы перезагрузка конфига не остановила горутину уровня,
+	//которой уже решено отправить запрос
+	var pending []chan error
+
+	l.mu.RLock()
+
+	for _, level := range levels {
+		if l.isWrite(level) == false {
+			continue
+		}
+
+		req := flushRequest{sync: sync, done: make(chan error, 1)}
+
+		select {
+		case l.getFlushChan(level) <- req:
+			pending = append(pending, req.done)
+		case <-l.ctx.Done():
+			//логгер остановлен, горутины сами сохраняют все перед выходом
+		case <-ctx.Done():
+			l.mu.RUnlock()
+			return ctx.Err()
+		}
+	}
+
+	l.mu.RUnlock()
+
+	var firstErr error
+
+	for _, done := range pending {
+		select {
+		case err := <-done:
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return firstErr
+}
+
+// fsync файла уровня за текущий день. если файла еще нет, синхронизировать нечего
+func (l *logger) syncFile(level string) error {
+	file, err := os.OpenFile(path.Join(l.pathFolder, level, l.getFileName(level)), os.O_WRONLY, 0)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return file.Sync()
+}
@@ -0,0 +1,39 @@
+package logger
+
+import "time"
+
+// Entry готовая запись в виде, удобном для хуков и проверок в тестах
+type Entry struct {
+	Time    time.Time
+	Level   string
+	Message string
+	Params  []string //строковые параметры вида key=value
+	Fields  []Field  //типизированные параметры, включая поля из With и контекста
+	Error   string   //текст ошибки, пустой если ошибки нет
+	Caller  string   //файл и строка вызова, если включен AddCaller
+}
+
+// Hook получает каждую запись включенного уровня до вывода в консоль и в файл.
+// вызывается в горутине вызывающего, поэтому должен быть быстрым и потокобезопасным.
+// уровни, на которые подписан хук, считаются включенными, даже если печать и запись выключены
+type Hook func(Entry)
+
+func newEntry(record *recordType) Entry {
+	entry := Entry{
+		Time:    record.time,
+		Level:   record.Level,
+		Message: record.Message,
+		Params:  record.Params,
+		Fields:  record.Fields,
+	}
+
+	if record.Error != nil {
+		entry.Error = record.Error.Message
+	}
+
+	if record.Caller != nil {
+		entry.Caller = record.Caller.String()
+	}
+
+	return entry
+}
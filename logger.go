@@ -3,184 +3,478 @@ package logger
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
+	"os"
 	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
 )
 
+// завершение программы, подменяется в тестах
+var exit = os.Exit
+
+// params строковые параметры вида key=value, см. AddParam.
+// типизированные поля, которые сохраняют тип значения в json, передаются в Infow, Debugw и Errorw
+// или привязываются к дочернему логгеру через With
 type ILogger interface {
 	Info(msg string, err error, params ...string)  //Информационные сообщения о ходе работы программы
 	Debug(msg string, err error, params ...string) //Сообщения отладки
 	Error(msg string, err error, params ...string) //Ошибка в ходе работы программы
+	Fatal(msg string, err error, params ...string) //Ошибка, после которой программа завершается, os.Exit(1)
+	Panic(msg string, err error, params ...string) //Ошибка, после которой вызывается panic(msg)
+
+	//то же самое с типизированными полями вместо строковых параметров
+	Infow(msg string, err error, fields ...Field)
+	Debugw(msg string, err error, fields ...Field)
+	Errorw(msg string, err error, fields ...Field)
+
+	//сообщение форматируется через fmt.Sprintf, только если уровень включен
+	Infof(format string, args ...interface{})
+	Debugf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+
+	//сообщение вычисляется функцией msg, только если уровень включен
+	InfoFn(msg func() string, err error, params ...string)
+	DebugFn(msg func() string, err error, params ...string)
+	ErrorFn(msg func() string, err error, params ...string)
+
+	//то же самое, но с полями из контекста (trace id, request id и т.п.)
+	InfoCtx(ctx context.Context, msg string, err error, params ...string)
+	DebugCtx(ctx context.Context, msg string, err error, params ...string)
+	ErrorCtx(ctx context.Context, msg string, err error, params ...string)
+
+	With(fields ...Field) ILogger            //Дочерний логгер, добавляющий поля fields в каждую запись
+	WithContext(ctx context.Context) ILogger //Дочерний логгер с полями из контекста
+
+	Flush(ctx context.Context) error //Запись буферов всех уровней в файлы без остановки логгера
+	Sync() error                     //То же самое, что Flush, но с fsync файлов
 
 	Stop()
 }
 
+// logger легкая обертка над общим ядром. дочерние логгеры из With
+// разделяют с родителем ядро (каналы, горутины, настройки) и отличаются только полями
 type logger struct {
+	*core
+
+	fields     fieldList //поля, которые добавляются в каждую запись
+	callerSkip int       //дополнительный сдвиг стека для оберток, см. AddCallerSkip
+}
+
+// core общее состояние логгера и всех его дочерних логгеров
+type core struct {
 	infoChan  chan *recordType
 	debugChan chan *recordType
 	errorChan chan *recordType
 
+	//поведение при заполненных каналах и счетчики отброшенных записей
+	infoOverflow    *overflowState
+	debugOverflow   *overflowState
+	errorOverflow   *overflowState
+	overflowTimeout time.Duration
+
+	//журналы упреждающей записи, nil если Durable выключен
+	infoWAL  *walType
+	debugWAL *walType
+	errorWAL *walType
+
+	//запросы на принудительный сброс буферов горутин
+	infoFlush  chan flushRequest
+	debugFlush chan flushRequest
+	errorFlush chan flushRequest
+
 	bufferCapacity int
 	chanCapacity   int
 
-	printInfo  bool
-	printError bool
-	printDebug bool
+	//переключаются при перезагрузке конфига без остановки логгера, см. WatchConfig
+	printInfo  atomic.Bool
+	printError atomic.Bool
+	printDebug atomic.Bool
+
+	//меняются только под mu.Lock при остановленных горутинах уровней
+	writeInfo  atomic.Bool
+	writeError atomic.Bool
+	writeDebug atomic.Bool
+
+	format        string
+	writeTimout   uint
+	flushInterval time.Duration //период записи неполной пачки, FlushInterval или WriteTimout в секундах
+	maxBatchBytes int
+	clock         Clock
+	hook          Hook
+	output        io.Writer //консоль для уровней Print*
+	flushOn       string    //уровень, начиная с которого записи пишутся в файл сразу
+	flushOnWait   bool
+	durable       bool
+	pathFolder    string
+	color         atomic.Bool
 
-	writeInfo  bool
-	writeError bool
-	writeDebug bool
+	fileTemplate    *template.Template
+	consoleTemplate *template.Template
 
-	format       string
-	writeTimout  uint
-	withoutWrite bool
-	pathFolder   string
-	color        bool
+	extractors []ContextExtractor
+
+	addCaller      bool
+	baseCallerSkip int
+
+	stackTrace      bool
+	stackFrameLimit int
 
 	//stop    bool
-	stopped chan struct{}
-	wg      *sync.WaitGroup
+	stopped chan struct{} //закрывается, когда все горутины сохранили логи и завершились
 	ctx     context.Context
 	cancel  context.CancelFunc
 
+	//текущие горутины уровней. при перезагрузке конфига они останавливаются
+	//и запускаются заново, меняются под mu.Lock
+	workers       *sync.WaitGroup
+	workersCancel context.CancelFunc
+	processing    bool //запущена ли startProcessingLogs. без записи в файлы ждать некого, и горутины нет
+
+	watching atomic.Bool //запущено ли слежение за файлом конфига, см. WatchConfig
+
+	//отправка в каналы идет под RLock, остановка берет Lock,
+	//поэтому после closed = true в каналы никто не пишет
+	mu        sync.RWMutex
+	closed    bool
+	stopOnce  sync.Once
+	afterStop string //что делать с записями после остановки: AfterStopStderr или AfterStopDrop
+
+	pending int64  //отправлено в каналы, но еще не записано в файлы, atomic
+	dropped uint64 //отброшено после остановки, atomic
+
 	debugLog bool
 }
 
-func New(config *LoggerConf) *logger {
+// New создает логгер из опций. конфиг тоже является опцией: New(&LoggerConf{...})
+func New(opts ...Option) *logger {
+	config := &LoggerConf{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt.apply(config)
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
-	wg := &sync.WaitGroup{}
 
-	if config.Format != JSONFormat && config.Format != TextFormat {
-		log.Fatal("Поле Format должно содержать 'text' или 'json'")
+	afterStop := config.AfterStop
+	if afterStop == "" {
+		afterStop = AfterStopStderr
 	}
 
-	if config.BufferCapacity == 0 {
-		log.Fatal("Поле BufferCapacity должно быть больше нуля")
+	if afterStop != AfterStopStderr && afterStop != AfterStopDrop {
+		log.Fatal("Поле AfterStop должно содержать 'stderr' или 'drop'")
 	}
 
-	if config.ChanCapacity == 0 {
-		log.Fatal("Поле ChanCapacity должно быть больше нуля")
+	if config.FlushOn != "" && levelRank(config.FlushOn) == 0 {
+		log.Fatal("Поле FlushOn должно содержать уровень логирования")
 	}
 
-	logger := &logger{
+	var output io.Writer = os.Stdout
+	if config.Output != nil {
+		output = config.Output
+	}
+
+	var clock Clock = realClock{}
+	if config.Clock != nil {
+		clock = config.Clock
+	}
+
+	flushInterval := config.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = time.Second * time.Duration(int(config.WriteTimout))
+	}
+
+	overflowTimeout := config.OverflowTimeout
+	if overflowTimeout <= 0 {
+		overflowTimeout = defaultOverflowTimeout
+	}
+
+	stackFrameLimit := config.StackFrameLimit
+	if stackFrameLimit <= 0 {
+		stackFrameLimit = defaultStackFrameLimit
+	}
+
+	extractors := config.ContextExtractors
+	if extractors == nil {
+		extractors = DefaultContextExtractors
+	}
+
+	//формат и размеры буферов нужны только для записи в файлы
+	if config.WriteInfo == true || config.WriteError == true || config.WriteDebug == true {
+		if config.Format != JSONFormat && config.Format != TextFormat {
+			log.Fatal("Поле Format должно содержать 'text' или 'json'")
+		}
+
+		if config.BufferCapacity == 0 {
+			log.Fatal("Поле BufferCapacity должно быть больше нуля")
+		}
+
+		if config.ChanCapacity == 0 {
+			log.Fatal("Поле ChanCapacity должно быть больше нуля")
+		}
+	}
+
+	logger := &logger{core: &core{
 		infoChan:  make(chan *recordType, config.ChanCapacity),
 		debugChan: make(chan *recordType, config.ChanCapacity),
 		errorChan: make(chan *recordType, config.ChanCapacity),
 
-		printInfo:  config.PrintInfo,
-		printError: config.PrintError,
-		printDebug: config.PrintDebug,
+		infoOverflow:    newOverflowState(InfoLevel, config.OverflowInfo),
+		debugOverflow:   newOverflowState(DebugLevel, config.OverflowDebug),
+		errorOverflow:   newOverflowState(ErrorLevel, config.OverflowError),
+		overflowTimeout: overflowTimeout,
 
-		writeInfo:  config.WriteInfo,
-		writeError: config.WriteError,
-		writeDebug: config.WriteDebug,
+		infoFlush:  make(chan flushRequest),
+		debugFlush: make(chan flushRequest),
+		errorFlush: make(chan flushRequest),
 
 		writeTimout:    config.WriteTimout,
+		flushInterval:  flushInterval,
+		maxBatchBytes:  config.MaxBatchBytes,
+		clock:          clock,
+		hook:           config.Hook,
+		output:         output,
+		flushOn:        config.FlushOn,
+		flushOnWait:    config.FlushOnWait,
 		format:         config.Format,
 		pathFolder:     config.PathFolder,
 		bufferCapacity: config.BufferCapacity,
-		color:          config.Color,
+		chanCapacity:   config.ChanCapacity,
+		durable:        config.Durable,
 
-		wg:       wg,
-		stopped:  make(chan struct{}),
-		ctx:      ctx,
-		cancel:   cancel,
-		debugLog: config.DebugLog,
-	}
+		fileTemplate:    compileTemplate("FileTemplate", config.FileTemplate),
+		consoleTemplate: compileTemplate("ConsoleTemplate", config.ConsoleTemplate),
+
+		extractors: extractors,
+
+		addCaller:      config.AddCaller,
+		baseCallerSkip: config.CallerSkip,
+
+		stackTrace:      config.StackTrace,
+		stackFrameLimit: stackFrameLimit,
+
+		stopped:   make(chan struct{}),
+		afterStop: afterStop,
+		ctx:       ctx,
+		cancel:    cancel,
+		debugLog:  config.DebugLog,
+	}}
+
+	logger.printInfo.Store(config.PrintInfo)
+	logger.printError.Store(config.PrintError)
+	logger.printDebug.Store(config.PrintDebug)
+
+	logger.writeInfo.Store(config.WriteInfo)
+	logger.writeError.Store(config.WriteError)
+	logger.writeDebug.Store(config.WriteDebug)
 
-	if logger.writeError == false && logger.writeInfo == false && logger.writeDebug == false {
-		logger.withoutWrite = true
+	logger.color.Store(config.Color)
+
+	if logger.durable == true {
+		if err := logger.openWALs(); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	if logger.withoutWrite == false {
+	if logger.startWorkers() != 0 {
+		logger.processing = true
 		go logger.startProcessingLogs()
 	}
 
 	return logger
 }
 
-// читает из каналов логи и пишет их в слайсы, для дальнейшей обработки и записи
-func (l *logger) startProcessingLogs() {
-	/*пуск горутин на каждый уровень логирования, указанный в конфигурации*/
-	if l.writeInfo == true {
-		l.debug(fmt.Sprintf("пуск горутины для канала %s", Info))
+// пускает горутины на каждый уровень логирования, указанный в конфигурации, и возвращает их количество.
+// вызывается в New и под mu.Lock при перезагрузке конфига
+func (l *logger) startWorkers() int {
+	ctx, cancel := context.WithCancel(l.ctx)
+	wg := &sync.WaitGroup{}
+	started := 0
 
-		l.wg.Add(1)
-		go l.listenChan(Info)
-	}
+	for _, level := range []string{InfoLevel, DebugLevel, ErrorLevel} {
+		if l.isWrite(level) == false {
+			continue
+		}
 
-	if l.writeDebug == true {
-		l.debug(fmt.Sprintf("пуск горутины для канала %s", Debug))
+		l.debug(fmt.Sprintf("пуск горутины для канала %s", level))
 
-		l.wg.Add(1)
-		go l.listenChan(Debug)
+		wg.Add(1)
+		go l.listenChan(ctx, wg, level)
+		started++
 	}
 
-	if l.writeError == true {
-		l.debug(fmt.Sprintf("пуск горутины для канала %s", Error))
+	l.workers = wg
+	l.workersCancel = cancel
 
-		l.wg.Add(1)
-		go l.listenChan(Error)
-	}
+	return started
+}
 
+// останавливает горутины уровней и ждет, пока они сохранят пачки и каналы.
+// вызывается под mu.Lock, поэтому новых записей в каналах не появится
+func (l *logger) stopWorkers() {
+	l.workersCancel()
+	l.workers.Wait()
+}
+
+// ждет остановки логгера и завершения горутин уровней
+func (l *logger) startProcessingLogs() {
 	l.debug("жду в вызывающей горутине")
 
-	l.wg.Wait()
+	<-l.ctx.Done()
+
+	//после остановки closed = true, и перезагрузка конфига горутины уже не меняет
+	l.mu.Lock()
+	workers := l.workers
+	l.mu.Unlock()
+
+	workers.Wait()
+
+	l.closeWALs()
 
 	l.debug("отправляю сигнал о выполненной остановке в вызывающей горутине")
 
-	l.stopped <- struct{}{}
+	close(l.stopped)
 }
 
-// Stop() graceful stop
+// Stop() graceful stop. повторные вызовы безопасны
 func (l *logger) Stop() {
-	if l.withoutWrite == true {
-		return
-	}
+	l.Shutdown(context.Background())
+}
+
+// Shutdown останавливает логгер и ждет сохранения всех логов, но не дольше ctx.
+// если ctx завершился раньше, возвращает *ShutdownError с количеством еще не сохраненных и отброшенных записей.
+// записи, сделанные после остановки, уходят в stderr или отбрасываются, см. AfterStop
+func (l *logger) Shutdown(ctx context.Context) error {
+	l.stopOnce.Do(func() {
+		l.debug("отправляю сигнал на остановку")
+
+		//Lock ждет вызывающих, которые застряли в отправке в канал под RLock,
+		//поэтому берется в отдельной горутине, чтобы Shutdown соблюдал срок ctx
+		go func() {
+			l.mu.Lock()
+			l.closed = true
+			processing := l.processing
+			l.mu.Unlock()
+
+			l.cancel()
+
+			//у логгера без записи в файлы сохранять нечего
+			if processing == false {
+				close(l.stopped)
+			}
+		}()
+	})
 
-	l.debug("отправляю сигнал на остановку")
-	l.cancel()
 	l.debug("жду завершения работы горутин")
-	<-l.stopped
-	l.debug("логгер завершил работу")
+
+	select {
+	case <-l.stopped:
+		l.debug("логгер завершил работу")
+		return nil
+	case <-ctx.Done():
+		return &ShutdownError{
+			Unsaved: atomic.LoadInt64(&l.pending),
+			Dropped: l.Stats().dropped(),
+			Err:     ctx.Err(),
+		}
+	}
 }
 
+// Dropped количество записей, отброшенных после остановки логгера
+func (l *logger) Dropped() uint64 {
+	return atomic.LoadUint64(&l.dropped)
+}
+
+// With возвращает дочерний логгер, который добавляет fields в каждую запись.
+// вложенные вызовы объединяют поля, одноименные поля перекрываются новыми.
+// дочерний логгер дешевый, его можно создавать на каждый запрос
+func (l *logger) With(fields ...Field) ILogger {
+	return &logger{
+		core:       l.core,
+		fields:     mergeFields(l.fields, fields),
+		callerSkip: l.callerSkip,
+	}
+}
+
+// AddParam строковый параметр вида key=value. для значений, которые должны сохранить тип в json,
+// есть поля String, Int, Int64, Float, Bool, Duration, Time, Any и Err, см. Infow
 func (l *logger) AddParam(key string, value interface{}) string {
 	return key + "=" + fmt.Sprint(value)
 }
 
 func (l *logger) Info(msg string, err error, params ...string) {
-	record := l.collectRecord(Info, msg, err, params...)
+	l.log(nil, InfoLevel, msg, err, params, nil)
+}
 
-	if l.printInfo == true {
-		fmt.Println(l.prepareToPrint(record))
-	}
+func (l *logger) Debug(msg string, err error, params ...string) {
+	l.log(nil, DebugLevel, msg, err, params, nil)
+}
 
-	if l.writeInfo == true {
-		l.infoChan <- record
-	}
+func (l *logger) Error(msg string, err error, params ...string) {
+	l.log(nil, ErrorLevel, msg, err, params, nil)
 }
 
-func (l *logger) Debug(msg string, err error, params ...string) {
-	record := l.collectRecord(Debug, msg, err, params...)
+func (l *logger) Infow(msg string, err error, fields ...Field) {
+	l.log(nil, InfoLevel, msg, err, nil, fields)
+}
+
+func (l *logger) Debugw(msg string, err error, fields ...Field) {
+	l.log(nil, DebugLevel, msg, err, nil, fields)
+}
 
-	if l.printDebug == true {
-		fmt.Println(l.prepareToPrint(record))
+func (l *logger) Errorw(msg string, err error, fields ...Field) {
+	l.log(nil, ErrorLevel, msg, err, nil, fields)
+}
+
+// Fatal пишет запись уровня fatal, дожидается записи всех буферов и завершает программу
+func (l *logger) Fatal(msg string, err error, params ...string) {
+	l.log(nil, FatalLevel, msg, err, params, nil)
+	l.flushAll()
+	exit(1)
+}
+
+// Panic пишет запись уровня panic, дожидается записи всех буферов и паникует с msg
+func (l *logger) Panic(msg string, err error, params ...string) {
+	l.log(nil, PanicLevel, msg, err, params, nil)
+	l.flushAll()
+	panic(msg)
+}
+
+func (l *logger) Infof(format string, args ...interface{}) {
+	if l.enabled(InfoLevel) == true {
+		l.log(nil, InfoLevel, fmt.Sprintf(format, args...), nil, nil, nil)
 	}
+}
 
-	if l.writeDebug == true {
-		l.debugChan <- record
+func (l *logger) Debugf(format string, args ...interface{}) {
+	if l.enabled(DebugLevel) == true {
+		l.log(nil, DebugLevel, fmt.Sprintf(format, args...), nil, nil, nil)
 	}
 }
 
-func (l *logger) Error(msg string, err error, params ...string) {
-	record := l.collectRecord(Error, msg, err, params...)
+func (l *logger) Errorf(format string, args ...interface{}) {
+	if l.enabled(ErrorLevel) == true {
+		l.log(nil, ErrorLevel, fmt.Sprintf(format, args...), nil, nil, nil)
+	}
+}
 
-	if l.printError == true {
-		fmt.Println(l.prepareToPrint(record))
+func (l *logger) InfoFn(msg func() string, err error, params ...string) {
+	if l.enabled(InfoLevel) == true {
+		l.log(nil, InfoLevel, msg(), err, params, nil)
 	}
+}
+
+func (l *logger) DebugFn(msg func() string, err error, params ...string) {
+	if l.enabled(DebugLevel) == true {
+		l.log(nil, DebugLevel, msg(), err, params, nil)
+	}
+}
 
-	if l.writeError == true {
-		l.errorChan <- record
+func (l *logger) ErrorFn(msg func() string, err error, params ...string) {
+	if l.enabled(ErrorLevel) == true {
+		l.log(nil, ErrorLevel, msg(), err, params, nil)
 	}
 }
@@ -0,0 +1,221 @@
+// Package logtest логгер для юнит-тестов, который хранит записи в памяти.
+// вместо записи в ./logs и поиска по файлам тест проверяет записи через Logs
+//
+//	l, logs := logtest.New()
+//	service := NewService(l)
+//	service.Do()
+//	logs.AssertLogged(t, logger.ErrorLevel, "Ошибка")
+package logtest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/IlyaKharitonov/logger"
+)
+
+// Logs записи, полученные логгером, в порядке поступления
+type Logs struct {
+	mu      sync.Mutex
+	entries []logger.Entry
+}
+
+// New возвращает логгер, который сохраняет записи всех уровней в памяти,
+// ничего не печатая в консоль и не записывая в файлы
+func New() (logger.ILogger, *Logs) {
+	logs := &Logs{}
+	return newLogger(logs.add), logs
+}
+
+// NewTB то же, что New, но дополнительно выводит каждую запись через t.Log,
+// поэтому логи упавшего теста видны в его выводе. логгер останавливается по окончании теста.
+// записи из горутин, переживших тест, сохраняются в Logs, но в t.Log не попадают,
+// так как t.Log после завершения теста паникует
+func NewTB(t testing.TB) (logger.ILogger, *Logs) {
+	logs := &Logs{}
+
+	var (
+		mu       sync.Mutex
+		finished bool
+	)
+
+	l := newLogger(func(entry logger.Entry) {
+		logs.add(entry)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if finished == false {
+			t.Log(formatEntry(entry))
+		}
+	})
+
+	t.Cleanup(func() {
+		mu.Lock()
+		finished = true
+		mu.Unlock()
+
+		l.Stop()
+	})
+
+	return l, logs
+}
+
+func newLogger(hook logger.Hook) logger.ILogger {
+	return logger.New(&logger.LoggerConf{
+		AddCaller: true,
+		Hook:      hook,
+	})
+}
+
+func (o *Logs) add(entry logger.Entry) {
+	o.mu.Lock()
+	o.entries = append(o.entries, entry)
+	o.mu.Unlock()
+}
+
+// Len количество записей
+func (o *Logs) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return len(o.entries)
+}
+
+// All копия всех записей
+func (o *Logs) All() []logger.Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]logger.Entry(nil), o.entries...)
+}
+
+// TakeAll возвращает все записи и очищает список
+func (o *Logs) TakeAll() []logger.Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entries := o.entries
+	o.entries = nil
+
+	return entries
+}
+
+// Filter новый список из записей, для которых fn вернула true
+func (o *Logs) Filter(fn func(logger.Entry) bool) *Logs {
+	filtered := &Logs{}
+
+	for _, entry := range o.All() {
+		if fn(entry) == true {
+			filtered.entries = append(filtered.entries, entry)
+		}
+	}
+
+	return filtered
+}
+
+// FilterLevel записи уровня level
+func (o *Logs) FilterLevel(level string) *Logs {
+	return o.Filter(func(entry logger.Entry) bool {
+		return entry.Level == level
+	})
+}
+
+// FilterMessage записи с сообщением, равным msg
+func (o *Logs) FilterMessage(msg string) *Logs {
+	return o.Filter(func(entry logger.Entry) bool {
+		return entry.Message == msg
+	})
+}
+
+// FilterMessageSnippet записи, сообщение которых содержит snippet
+func (o *Logs) FilterMessageSnippet(snippet string) *Logs {
+	return o.Filter(func(entry logger.Entry) bool {
+		return strings.Contains(entry.Message, snippet)
+	})
+}
+
+// FilterField записи с полем, совпадающим с field по ключу и значению
+func (o *Logs) FilterField(field logger.Field) *Logs {
+	return o.Filter(func(entry logger.Entry) bool {
+		for _, f := range entry.Fields {
+			if f.Key == field.Key && reflect.DeepEqual(f.Value, field.Value) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// FilterFieldKey записи, в которых есть поле с ключом key
+func (o *Logs) FilterFieldKey(key string) *Logs {
+	return o.Filter(func(entry logger.Entry) bool {
+		for _, f := range entry.Fields {
+			if f.Key == key {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// AssertLogged проверяет, что была запись уровня level с сообщением msg
+func (o *Logs) AssertLogged(t testing.TB, level string, msg string) {
+	t.Helper()
+
+	if o.FilterLevel(level).FilterMessage(msg).Len() == 0 {
+		t.Errorf("нет записи уровня %s с сообщением %q, записи:\n%s", level, msg, o)
+	}
+}
+
+// AssertNotLogged проверяет, что записи уровня level с сообщением msg не было
+func (o *Logs) AssertNotLogged(t testing.TB, level string, msg string) {
+	t.Helper()
+
+	if o.FilterLevel(level).FilterMessage(msg).Len() != 0 {
+		t.Errorf("неожиданная запись уровня %s с сообщением %q, записи:\n%s", level, msg, o)
+	}
+}
+
+// AssertLen проверяет количество записей
+func (o *Logs) AssertLen(t testing.TB, n int) {
+	t.Helper()
+
+	if c := o.Len(); c != n {
+		t.Errorf("записей %d вместо %d, записи:\n%s", c, n, o)
+	}
+}
+
+// String записи по одной на строку, для сообщений об ошибках в тестах
+func (o *Logs) String() string {
+	var sb strings.Builder
+
+	for _, entry := range o.All() {
+		sb.WriteString(formatEntry(entry))
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+func formatEntry(entry logger.Entry) string {
+	parts := []string{"[" + entry.Level + "]", entry.Message}
+
+	parts = append(parts, entry.Params...)
+	for _, f := range entry.Fields {
+		parts = append(parts, f.String())
+	}
+
+	if entry.Error != "" {
+		parts = append(parts, fmt.Sprintf("error=%q", entry.Error))
+	}
+
+	if entry.Caller != "" {
+		parts = append(parts, entry.Caller)
+	}
+
+	return strings.Join(parts, " ")
+}
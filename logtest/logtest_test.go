@@ -0,0 +1,99 @@
+package logtest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/IlyaKharitonov/logger"
+)
+
+func TestObserver(t *testing.T) {
+	l, logs := New()
+	defer l.Stop()
+
+	child := l.With(logger.String("request_id", "42"))
+	child.Infow("запрос принят", nil, logger.Int("size", 10))
+	child.Error("Ошибка", errors.New("нет соединения"))
+	l.Debug("отладка", nil)
+
+	logs.AssertLen(t, 3)
+	logs.AssertLogged(t, logger.ErrorLevel, "Ошибка")
+	logs.AssertNotLogged(t, logger.InfoLevel, "Ошибка")
+
+	if c := logs.FilterLevel(logger.DebugLevel).Len(); c != 1 {
+		t.Errorf("записей debug %d вместо 1", c)
+	}
+
+	if c := logs.FilterField(logger.String("request_id", "42")).Len(); c != 2 {
+		t.Errorf("записей с request_id %d вместо 2", c)
+	}
+
+	if c := logs.FilterField(logger.Int("size", 10)).FilterMessage("запрос принят").Len(); c != 1 {
+		t.Errorf("записей с size=10 %d вместо 1", c)
+	}
+
+	if c := logs.FilterMessageSnippet("отлад").Len(); c != 1 {
+		t.Errorf("записей с подстрокой %d вместо 1", c)
+	}
+
+	entry := logs.FilterLevel(logger.ErrorLevel).All()[0]
+	if entry.Error != "нет соединения" {
+		t.Errorf("текст ошибки %q", entry.Error)
+	}
+	if strings.Contains(entry.Caller, "logtest_test.go") == false {
+		t.Errorf("место вызова %q не указывает на тест", entry.Caller)
+	}
+
+	if taken := logs.TakeAll(); len(taken) != 3 {
+		t.Errorf("TakeAll вернул %d записей вместо 3", len(taken))
+	}
+	logs.AssertLen(t, 0)
+}
+
+func TestObserverTB(t *testing.T) {
+	l, logs := NewTB(t)
+
+	l.Infof("запись %d", 1)
+
+	logs.AssertLogged(t, logger.InfoLevel, "запись 1")
+}
+
+// testing.TB, который запоминает функции Cleanup и отмечает вызовы Log после них
+type cleanupTB struct {
+	testing.TB
+
+	cleanups []func()
+	finished bool
+	lateLogs int
+}
+
+func (tb *cleanupTB) Cleanup(fn func()) { tb.cleanups = append(tb.cleanups, fn) }
+
+func (tb *cleanupTB) Log(args ...interface{}) {
+	if tb.finished == true {
+		tb.lateLogs++
+	}
+}
+
+func (tb *cleanupTB) finish() {
+	for _, fn := range tb.cleanups {
+		fn()
+	}
+	tb.finished = true
+}
+
+// запись после окончания теста не доходит до t.Log, который бы запаниковал
+func TestObserverTBAfterTest(t *testing.T) {
+	tb := &cleanupTB{TB: t}
+	l, logs := NewTB(tb)
+
+	l.Info("во время теста", nil)
+	tb.finish()
+	l.Info("после теста", nil)
+
+	if tb.lateLogs != 0 {
+		t.Errorf("после окончания теста вызван t.Log: %d", tb.lateLogs)
+	}
+	logs.AssertLogged(t, logger.InfoLevel, "после теста")
+}
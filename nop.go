@@ -0,0 +1,49 @@
+package logger
+
+import "context"
+
+// логгер, который ничего не делает
+type nopLogger struct{}
+
+// Nop возвращает логгер, который никуда не пишет и ничего не стоит.
+// подходит для библиотек и бенчмарков. Fatal и Panic все равно завершают программу
+// и паникуют, так как код после них не рассчитан на продолжение
+func Nop() ILogger {
+	return nopLogger{}
+}
+
+func (nopLogger) Info(msg string, err error, params ...string)  {}
+func (nopLogger) Debug(msg string, err error, params ...string) {}
+func (nopLogger) Error(msg string, err error, params ...string) {}
+
+func (nopLogger) Infow(msg string, err error, fields ...Field)  {}
+func (nopLogger) Debugw(msg string, err error, fields ...Field) {}
+func (nopLogger) Errorw(msg string, err error, fields ...Field) {}
+
+func (nopLogger) Fatal(msg string, err error, params ...string) {
+	exit(1)
+}
+
+func (nopLogger) Panic(msg string, err error, params ...string) {
+	panic(msg)
+}
+
+func (nopLogger) Infof(format string, args ...interface{})  {}
+func (nopLogger) Debugf(format string, args ...interface{}) {}
+func (nopLogger) Errorf(format string, args ...interface{}) {}
+
+func (nopLogger) InfoFn(msg func() string, err error, params ...string)  {}
+func (nopLogger) DebugFn(msg func() string, err error, params ...string) {}
+func (nopLogger) ErrorFn(msg func() string, err error, params ...string) {}
+
+func (nopLogger) InfoCtx(ctx context.Context, msg string, err error, params ...string)  {}
+func (nopLogger) DebugCtx(ctx context.Context, msg string, err error, params ...string) {}
+func (nopLogger) ErrorCtx(ctx context.Context, msg string, err error, params ...string) {}
+
+func (l nopLogger) With(fields ...Field) ILogger            { return l }
+func (l nopLogger) WithContext(ctx context.Context) ILogger { return l }
+
+func (nopLogger) Flush(ctx context.Context) error { return nil }
+func (nopLogger) Sync() error                     { return nil }
+
+func (nopLogger) Stop() {}
@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"io"
+	"time"
+)
+
+// Option настройка логгера для New. опции применяются по порядку к собственной
+// копии конфига логгера, поэтому логгеры одного процесса ничего не разделяют
+//
+//	audit := New(WithPath("./audit"), WithFormat(JSONFormat), WithWrite(InfoLevel), WithBuffers(15, 100))
+//	app := New(&LoggerConf{...}, WithPrint(ErrorLevel))
+type Option interface {
+	apply(config *LoggerConf)
+}
+
+type optionFunc func(config *LoggerConf)
+
+func (f optionFunc) apply(config *LoggerConf) {
+	f(config)
+}
+
+// конфиг целиком как опция: поля копируются, сам конфиг логгер не запоминает
+func (c *LoggerConf) apply(config *LoggerConf) {
+	if c != nil {
+		*config = *c
+	}
+}
+
+// WithFormat формат записи в файл: JSONFormat или TextFormat
+func WithFormat(format string) Option {
+	return optionFunc(func(config *LoggerConf) {
+		config.Format = format
+	})
+}
+
+// WithPath папка для файлов логов
+func WithPath(folder string) Option {
+	return optionFunc(func(config *LoggerConf) {
+		config.PathFolder = folder
+	})
+}
+
+// WithPrint уровни, которые печатаются в консоль, остальные не печатаются.
+// ErrorLevel включает и fatal с panic
+func WithPrint(levels ...string) Option {
+	return optionFunc(func(config *LoggerConf) {
+		config.PrintInfo = hasLevel(levels, InfoLevel)
+		config.PrintDebug = hasLevel(levels, DebugLevel)
+		config.PrintError = hasLevel(levels, ErrorLevel)
+	})
+}
+
+// WithWrite уровни, которые пишутся в файлы, остальные не пишутся.
+// ErrorLevel включает и fatal с panic, они пишутся в его файл
+func WithWrite(levels ...string) Option {
+	return optionFunc(func(config *LoggerConf) {
+		config.WriteInfo = hasLevel(levels, InfoLevel)
+		config.WriteDebug = hasLevel(levels, DebugLevel)
+		config.WriteError = hasLevel(levels, ErrorLevel)
+	})
+}
+
+// WithBuffers размер пачки записей и размер буфера каналов уровней
+func WithBuffers(bufferCapacity int, chanCapacity int) Option {
+	return optionFunc(func(config *LoggerConf) {
+		config.BufferCapacity = bufferCapacity
+		config.ChanCapacity = chanCapacity
+	})
+}
+
+// WithFlushInterval период записи неполной пачки
+func WithFlushInterval(interval time.Duration) Option {
+	return optionFunc(func(config *LoggerConf) {
+		config.FlushInterval = interval
+	})
+}
+
+// WithColor раскрашивать уровень в консоли
+func WithColor(color bool) Option {
+	return optionFunc(func(config *LoggerConf) {
+		config.Color = color
+	})
+}
+
+// WithOutput куда печатаются уровни из WithPrint, по умолчанию os.Stdout
+func WithOutput(output io.Writer) Option {
+	return optionFunc(func(config *LoggerConf) {
+		config.Output = output
+	})
+}
+
+// WithCaller записывать место вызова, skip - сколько кадров пропустить для оберток
+func WithCaller(skip int) Option {
+	return optionFunc(func(config *LoggerConf) {
+		config.AddCaller = true
+		config.CallerSkip = skip
+	})
+}
+
+// WithClock источник времени, в тестах clocktest.Clock
+func WithClock(clock Clock) Option {
+	return optionFunc(func(config *LoggerConf) {
+		config.Clock = clock
+	})
+}
+
+// WithHook функция, которая получает каждую запись
+func WithHook(hook Hook) Option {
+	return optionFunc(func(config *LoggerConf) {
+		config.Hook = hook
+	})
+}
+
+func hasLevel(levels []string, level string) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
+		}
+	}
+
+	return false
+}
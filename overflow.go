@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// таймаут ожидания места в канале для OverflowBlockTimeout, если OverflowTimeout не задан
+const defaultOverflowTimeout = time.Second
+
+// запись отброшена политикой переполнения и не попадет в файл
+var errRecordDropped = errors.New("logger: запись отброшена из-за переполнения канала")
+
+// состояние канала уровня при переполнении
+type overflowState struct {
+	policy  string
+	dropped uint64 //отброшено из-за переполнения, atomic
+	spilled uint64 //ушло в очередь переполнения, atomic
+
+	//неограниченная очередь для OverflowSpill, горутина уровня забирает ее по сигналу
+	spillMu     sync.Mutex
+	spill       []*recordType
+	spillSignal chan struct{}
+}
+
+func newOverflowState(level string, policy string) *overflowState {
+	if policy == "" {
+		policy = OverflowBlock
+	}
+
+	switch policy {
+	case OverflowBlock, OverflowBlockTimeout, OverflowDropNewest, OverflowDropOldest, OverflowSpill:
+	default:
+		log.Fatal("Неизвестная политика переполнения канала "+level+": ", policy)
+	}
+
+	return &overflowState{policy: policy, spillSignal: make(chan struct{}, 1)}
+}
+
+// Stats счетчики записей, которые не прошли через каналы обычным путем
+type Stats struct {
+	Dropped   map[string]uint64 //отброшено из-за переполнения канала, по уровням
+	Spilled   map[string]uint64 //ушло в очередь переполнения, по уровням
+	AfterStop uint64            //отброшено после остановки логгера
+}
+
+func (l *logger) Stats() Stats {
+	stats := Stats{
+		Dropped:   map[string]uint64{},
+		Spilled:   map[string]uint64{},
+		AfterStop: l.Dropped(),
+	}
+
+	for _, level := range []string{InfoLevel, DebugLevel, ErrorLevel} {
+		st := l.getOverflow(level)
+		stats.Dropped[level] = atomic.LoadUint64(&st.dropped)
+		stats.Spilled[level] = atomic.LoadUint64(&st.spilled)
+	}
+
+	return stats
+}
+
+// сколько записей отброшено всего: из-за переполнения каналов и после остановки
+func (s Stats) dropped() uint64 {
+	total := s.AfterStop
+	for _, n := range s.Dropped {
+		total += n
+	}
+
+	return total
+}
+
+// кладет запись в канал уровня по политике переполнения этого уровня
+func (l *logger) enqueue(ch chan *recordType, record *recordType) {
+	st := l.getOverflow(record.Level)
+
+	atomic.AddInt64(&l.pending, 1)
+
+	switch st.policy {
+	case OverflowBlockTimeout:
+		select {
+		case ch <- record:
+			return
+		default:
+		}
+
+		timer := newTimer(l.clock)
+		timer.Reset(l.overflowTimeout)
+		defer timer.Stop()
+
+		select {
+		case ch <- record:
+		case <-timer.c:
+			l.dropOverflow(st, record)
+		}
+
+	case OverflowDropNewest:
+		select {
+		case ch <- record:
+		default:
+			l.dropOverflow(st, record)
+		}
+
+	case OverflowDropOldest:
+		for {
+			select {
+			case ch <- record:
+				return
+			default:
+			}
+
+			//освобождаю место, выкидывая самую старую запись
+			select {
+			case oldest := <-ch:
+				l.dropOverflow(st, oldest)
+			default:
+			}
+		}
+
+	case OverflowSpill:
+		select {
+		case ch <- record:
+		default:
+			st.pushSpill(record)
+		}
+
+	default:
+		ch <- record
+	}
+}
+
+func (l *logger) dropOverflow(st *overflowState, record *recordType) {
+	atomic.AddInt64(&l.pending, -1)
+	atomic.AddUint64(&st.dropped, 1)
+
+	//отброшенную запись не нужно восстанавливать из журнала при следующем запуске
+	l.getWAL(record.Level).ack([]*recordType{record})
+	record.notifyWritten(errRecordDropped)
+}
+
+func (st *overflowState) pushSpill(record *recordType) {
+	st.spillMu.Lock()
+	st.spill = append(st.spill, record)
+	st.spillMu.Unlock()
+
+	atomic.AddUint64(&st.spilled, 1)
+
+	select {
+	case st.spillSignal <- struct{}{}:
+	default:
+	}
+}
+
+// забирает все записи из очереди переполнения
+func (st *overflowState) takeSpill() []*recordType {
+	st.spillMu.Lock()
+	defer st.spillMu.Unlock()
+
+	spill := st.spill
+	st.spill = nil
+
+	return spill
+}
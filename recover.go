@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RecoverOptions настройки RecoverAndLog
+type RecoverOptions struct {
+	Level   string //уровень записи о панике, по умолчанию и для неизвестных уровней error. для критичных - FatalLevel, выхода не будет
+	Message string //сообщение записи, по умолчанию "Перехвачена паника"
+	Repanic bool   //после записи паниковать повторно с тем же значением
+}
+
+// RecoverAndLog перехватывает панику, пишет ее значение и полный стек,
+// дожидается записи буфера error и, если задано, паникует повторно.
+// вызывается только через defer:
+//
+//	defer logger.RecoverAndLog(RecoverOptions{})
+func (l *logger) RecoverAndLog(opts RecoverOptions) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	l.logPanic(r, opts)
+
+	if opts.Repanic == true {
+		panic(r)
+	}
+}
+
+// Go запускает fn в отдельной горутине, паника в которой будет записана и перехвачена
+func (l *logger) Go(fn func()) {
+	go func() {
+		defer l.RecoverAndLog(RecoverOptions{})
+		fn()
+	}()
+}
+
+func (l *logger) logPanic(r interface{}, opts RecoverOptions) {
+	//неизвестный уровень вроде "critical" никуда бы не попал, и паника пропала бы бесследно
+	level := opts.Level
+	if levelRank(level) == 0 {
+		level = ErrorLevel
+	}
+
+	msg := opts.Message
+	if msg == "" {
+		msg = "Перехвачена паника"
+	}
+
+	if l.enabled(level) == true {
+		err, ok := r.(error)
+		if !ok {
+			err = errors.New(fmt.Sprint(r))
+		}
+
+		record := l.collectRecord(level, msg, err, []Field{Any("panic", fmt.Sprint(r))})
+
+		//стек снимается внутри defer, поэтому в нем есть функция, которая запаниковала
+		record.Stack = captureStack(0, l.stackFrameLimit)
+		if l.addCaller == true && len(record.Stack) != 0 {
+			record.Caller = &record.Stack[0]
+		}
+
+		l.send(record)
+	}
+
+	l.flushLevel(level)
+}
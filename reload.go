@@ -0,0 +1,337 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// как часто WatchConfig проверяет время изменения файла
+const configPollInterval = time.Second
+
+// WatchConfig применяет конфиг из файла path и следит за его изменениями, пока логгер не остановлен.
+// файл .json читается как json, остальные как yaml с теми же ключами, что и в LoggerConf.
+//
+// на лету меняются уровни печати и записи, Format, Color, WriteTimout (FlushInterval),
+// BufferCapacity, ChanCapacity и PathFolder, остальные поля файла игнорируются.
+// ключи, которых нет в файле, сохраняют текущие значения, поэтому файл может содержать только PrintDebug.
+// если меняется запись в файлы, горутины уровней сохраняют накопленное по старым настройкам
+// и перезапускаются, записи не теряются.
+// конфиг с ошибкой не применяется: ошибка пишется в лог уровня error, остается прежний конфиг.
+// за логгером следит только один файл, повторный вызов возвращает ошибку
+func (l *logger) WatchConfig(path string) error {
+	if l.watching.CompareAndSwap(false, true) == false {
+		return errors.New("конфиг логгера уже отслеживается")
+	}
+
+	info, err := os.Stat(path)
+	if err == nil {
+		err = l.reloadConfig(path)
+	}
+
+	if err != nil {
+		l.watching.Store(false)
+		return err
+	}
+
+	go l.watchConfig(path, info)
+
+	return nil
+}
+
+func (l *logger) watchConfig(path string, last os.FileInfo) {
+	timer := newTimer(l.clock)
+	defer timer.Stop()
+
+	for {
+		timer.Reset(configPollInterval)
+
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-timer.c:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			//о пропаже файла сообщаю один раз, а не на каждой проверке
+			if last != nil {
+				l.configRejected(path, err)
+			}
+			last = nil
+			continue
+		}
+
+		if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+
+		if err := l.reloadConfig(path); err != nil {
+			l.configRejected(path, err)
+		}
+	}
+}
+
+// читает файл поверх текущих настроек и применяет результат
+func (l *logger) reloadConfig(path string) error {
+	config, err := readConfigFile(path, l.currentConfig())
+	if err != nil {
+		return err
+	}
+
+	return l.applyConfig(config)
+}
+
+// текущие значения полей, которые меняет WatchConfig
+func (l *logger) currentConfig() LoggerConf {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	//интервал, вычисленный из WriteTimout, не записывается в FlushInterval,
+	//иначе новый WriteTimout из файла перекрывался бы старым интервалом
+	flushInterval := l.flushInterval
+	if flushInterval == time.Second*time.Duration(int(l.writeTimout)) {
+		flushInterval = 0
+	}
+
+	return LoggerConf{
+		PrintInfo:      l.printInfo.Load(),
+		PrintError:     l.printError.Load(),
+		PrintDebug:     l.printDebug.Load(),
+		WriteInfo:      l.writeInfo.Load(),
+		WriteError:     l.writeError.Load(),
+		WriteDebug:     l.writeDebug.Load(),
+		WriteTimout:    l.writeTimout,
+		FlushInterval:  flushInterval,
+		Format:         l.format,
+		BufferCapacity: l.bufferCapacity,
+		ChanCapacity:   l.chanCapacity,
+		Color:          l.color.Load(),
+		PathFolder:     l.pathFolder,
+	}
+}
+
+// читает конфиг из файла поверх base. неизвестные ключи считаются ошибкой, чтобы опечатка не прошла незаметно
+func readConfigFile(path string, base LoggerConf) (*LoggerConf, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &base
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(config)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(config)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("разобрать конфиг %s не удалось: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// настройки, которые читают горутины уровней. меняются только при остановленных горутинах
+type workerSettings struct {
+	writeInfo      bool
+	writeError     bool
+	writeDebug     bool
+	format         string
+	bufferCapacity int
+	chanCapacity   int
+	writeTimout    uint
+	flushInterval  time.Duration
+	pathFolder     string
+}
+
+func (l *logger) workerSettings() workerSettings {
+	return workerSettings{
+		writeInfo:      l.writeInfo.Load(),
+		writeError:     l.writeError.Load(),
+		writeDebug:     l.writeDebug.Load(),
+		format:         l.format,
+		bufferCapacity: l.bufferCapacity,
+		chanCapacity:   l.chanCapacity,
+		writeTimout:    l.writeTimout,
+		flushInterval:  l.flushInterval,
+		pathFolder:     l.pathFolder,
+	}
+}
+
+func (l *logger) setWorkerSettings(settings workerSettings) {
+	l.writeInfo.Store(settings.writeInfo)
+	l.writeError.Store(settings.writeError)
+	l.writeDebug.Store(settings.writeDebug)
+	l.format = settings.format
+	l.bufferCapacity = settings.bufferCapacity
+
+	//каналы пусты: горутины перед остановкой их вычитали, а новые записи ждут Lock
+	if l.chanCapacity != settings.chanCapacity {
+		l.chanCapacity = settings.chanCapacity
+		l.infoChan = make(chan *recordType, l.chanCapacity)
+		l.debugChan = make(chan *recordType, l.chanCapacity)
+		l.errorChan = make(chan *recordType, l.chanCapacity)
+	}
+
+	l.writeTimout = settings.writeTimout
+	l.flushInterval = settings.flushInterval
+	l.pathFolder = settings.pathFolder
+}
+
+// применяет к работающему логгеру изменяемые на лету поля конфига
+func (l *logger) applyConfig(config *LoggerConf) error {
+	next := workerSettings{
+		writeInfo:      config.WriteInfo,
+		writeError:     config.WriteError,
+		writeDebug:     config.WriteDebug,
+		format:         config.Format,
+		bufferCapacity: config.BufferCapacity,
+		chanCapacity:   config.ChanCapacity,
+		writeTimout:    config.WriteTimout,
+		flushInterval:  config.FlushInterval,
+		pathFolder:     config.PathFolder,
+	}
+
+	if next.flushInterval <= 0 {
+		next.flushInterval = time.Second * time.Duration(int(config.WriteTimout))
+	}
+
+	//размер каналов в файле необязателен, по умолчанию остается текущий
+	if next.chanCapacity <= 0 {
+		l.mu.RLock()
+		next.chanCapacity = l.chanCapacity
+		l.mu.RUnlock()
+	}
+
+	var levels []string
+	if next.writeInfo == true {
+		levels = append(levels, InfoLevel)
+	}
+	if next.writeDebug == true {
+		levels = append(levels, DebugLevel)
+	}
+	if next.writeError == true {
+		levels = append(levels, ErrorLevel)
+	}
+
+	if len(levels) != 0 {
+		if next.format != JSONFormat && next.format != TextFormat {
+			return errors.New("поле Format должно содержать 'text' или 'json'")
+		}
+
+		if next.bufferCapacity <= 0 {
+			return errors.New("поле BufferCapacity должно быть больше нуля")
+		}
+
+		//логгер мог быть создан без записи в файлы и без размера каналов
+		if next.chanCapacity <= 0 {
+			return errors.New("поле ChanCapacity должно быть больше нуля")
+		}
+
+		//недоступная папка обнаруживается до остановки горутин, а не при первой записи
+		if l.durable == true {
+			levels = append(levels, walFolder)
+		}
+
+		if err := checkFolder(next.pathFolder, levels); err != nil {
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed == true {
+		return errors.New("логгер остановлен")
+	}
+
+	prev := l.workerSettings()
+
+	if prev != next {
+		l.debug("перезапускаю горутины для нового конфига")
+
+		//под Lock новые записи в каналы не попадают, старые горутины сохраняют все по старым настройкам
+		l.stopWorkers()
+
+		if l.durable == true {
+			l.closeWALs()
+		}
+
+		l.setWorkerSettings(next)
+
+		if l.durable == true {
+			if err := l.openWALs(); err != nil {
+				//возвращаю прежние настройки, их журналы только что были открыты
+				l.setWorkerSettings(prev)
+
+				if reopenErr := l.openWALs(); reopenErr != nil {
+					fmt.Fprintln(os.Stderr, "Журнал прежнего конфига не открылся, записи не защищены журналом ", reopenErr)
+				}
+
+				l.startWorkers()
+				return err
+			}
+		}
+
+		//логгер мог быть создан без записи в файлы, тогда ожидание остановки запускается здесь
+		if l.startWorkers() != 0 && l.processing == false {
+			l.processing = true
+			go l.startProcessingLogs()
+		}
+	}
+
+	//печать читается без блокировки, поэтому переключается сразу
+	l.printInfo.Store(config.PrintInfo)
+	l.printError.Store(config.PrintError)
+	l.printDebug.Store(config.PrintDebug)
+	l.color.Store(config.Color)
+
+	return nil
+}
+
+// проверяет, что в подпапки dirs папки pathFolder можно писать
+func checkFolder(pathFolder string, dirs []string) error {
+	for _, dir := range dirs {
+		dir = filepath.Join(pathFolder, dir)
+
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return fmt.Errorf("папка %s недоступна: %w", dir, err)
+		}
+
+		file, err := os.CreateTemp(dir, ".check-*")
+		if err != nil {
+			return fmt.Errorf("в папку %s нельзя писать: %w", dir, err)
+		}
+
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	return nil
+}
+
+// сообщает об отклоненном конфиге. если уровень error выключен, пишет в stderr
+func (l *logger) configRejected(path string, err error) {
+	msg := "Конфиг " + path + " не применен, оставлен прежний"
+
+	if l.enabled(ErrorLevel) == false {
+		fmt.Fprintln(os.Stderr, msg, err)
+		return
+	}
+
+	l.Error(msg, err)
+}
@@ -0,0 +1,128 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// slogHandler реализация slog.Handler поверх каналов и горутин логгера.
+// атрибуты и группы раскладываются в плоские поля записи с ключами вида "group.key"
+type slogHandler struct {
+	logger *logger
+	prefix string    //префикс текущей группы, например "request.headers."
+	fields fieldList //атрибуты, добавленные через WithAttrs
+}
+
+// Handler возвращает slog.Handler, который пишет записи через этот логгер:
+//
+//	slog.SetDefault(slog.New(l.Handler()))
+func (l *logger) Handler() slog.Handler {
+	return &slogHandler{logger: l}
+}
+
+// сопоставляет уровни slog с уровнями логгера
+// LevelWarn пока пишется как info, отдельного уровня warning нет
+func slogLevel(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return ErrorLevel
+	case level >= slog.LevelInfo:
+		return InfoLevel
+	default:
+		return DebugLevel
+	}
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.enabled(slogLevel(level))
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make(fieldList, 0, len(h.fields)+r.NumAttrs())
+	fields = append(fields, h.fields...)
+	fields = append(fields, h.logger.contextFields(ctx)...)
+
+	r.Attrs(func(attr slog.Attr) bool {
+		fields = appendAttr(fields, h.prefix, attr)
+		return true
+	})
+
+	record := h.logger.collectRecord(slogLevel(r.Level), r.Message, nil, fields)
+	record.setTime(r.Time)
+
+	if h.logger.addCaller == true {
+		record.Caller = frameFromPC(r.PC)
+	}
+
+	h.logger.send(record)
+
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	fields := make(fieldList, len(h.fields), len(h.fields)+len(attrs))
+	copy(fields, h.fields)
+
+	for _, attr := range attrs {
+		fields = appendAttr(fields, h.prefix, attr)
+	}
+
+	return &slogHandler{logger: h.logger, prefix: h.prefix, fields: fields}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &slogHandler{logger: h.logger, prefix: h.prefix + name + ".", fields: h.fields}
+}
+
+// переводит атрибут slog в поля записи, группы раскладываются рекурсивно
+func appendAttr(fields fieldList, prefix string, attr slog.Attr) fieldList {
+	attr.Value = attr.Value.Resolve()
+
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	value := attr.Value
+
+	if value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if attr.Key != "" {
+			groupPrefix = prefix + attr.Key + "."
+		}
+
+		for _, groupAttr := range value.Group() {
+			fields = appendAttr(fields, groupPrefix, groupAttr)
+		}
+
+		return fields
+	}
+
+	key := prefix + attr.Key
+
+	switch value.Kind() {
+	case slog.KindString:
+		return append(fields, String(key, value.String()))
+	case slog.KindInt64:
+		return append(fields, Int64(key, value.Int64()))
+	case slog.KindUint64:
+		return append(fields, Any(key, value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, Float(key, value.Float64()))
+	case slog.KindBool:
+		return append(fields, Bool(key, value.Bool()))
+	case slog.KindDuration:
+		return append(fields, Duration(key, value.Duration()))
+	case slog.KindTime:
+		return append(fields, Time(key, value.Time()))
+	default:
+		return append(fields, Any(key, value.Any()))
+	}
+}
@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// ограничение на количество кадров в стеке, если StackFrameLimit не задан
+const defaultStackFrameLimit = 32
+
+// путь пакета логгера, кадры из него вырезаются из стека
+var loggerPkgPath = reflect.TypeOf(logger{}).PkgPath()
+
+// снимает стек вызовов начиная с кадра на skip уровней выше вызывающей функции
+func captureStack(skip int, limit int) []frameType {
+	pcs := make([]uintptr, limit+16)
+	n := runtime.Callers(skip+2, pcs)
+
+	return framesFromPCs(pcs[:n], limit)
+}
+
+// переводит адреса в кадры, убирая кадры рантайма и самого логгера
+func framesFromPCs(pcs []uintptr, limit int) []frameType {
+	var list []frameType
+
+	frames := runtime.CallersFrames(pcs)
+	for len(list) < limit {
+		frame, more := frames.Next()
+
+		if frame.Function != "" && !skipFrame(frame) {
+			list = append(list, frameType{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return list
+}
+
+func skipFrame(frame runtime.Frame) bool {
+	if strings.HasPrefix(frame.Function, "runtime.") {
+		return true
+	}
+
+	//тесты пакета тоже имеют его путь, их кадры оставляем
+	return strings.HasPrefix(frame.Function, loggerPkgPath+".") && !strings.HasSuffix(frame.File, "_test.go")
+}
+
+// достает стек из ошибки, если ошибка или одна из обернутых в нее ошибок
+// имеет метод StackTrace(). поддерживается как []uintptr, так и срезы
+// типов на основе uintptr, например errors.StackTrace из github.com/pkg/errors
+func errorStack(err error) []uintptr {
+	for err != nil {
+		if pcs := callStackTrace(err); pcs != nil {
+			return pcs
+		}
+
+		err = errors.Unwrap(err)
+	}
+
+	return nil
+}
+
+func callStackTrace(err error) []uintptr {
+	if tracer, ok := err.(interface{ StackTrace() []uintptr }); ok {
+		return tracer.StackTrace()
+	}
+
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return nil
+	}
+
+	out := method.Call(nil)[0]
+	if out.Kind() != reflect.Slice || out.Type().Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+
+	pcs := make([]uintptr, out.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(out.Index(i).Uint())
+	}
+
+	return pcs
+}
+
+// стек для текстового формата и консоли, каждый кадр с отступом
+func stackString(stack []frameType) string {
+	var sb strings.Builder
+
+	for _, frame := range stack {
+		sb.WriteString("\n\t" + frame.Function + "\n\t\t" + frame.String())
+	}
+
+	return sb.String()
+}
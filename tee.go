@@ -0,0 +1,239 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// логгер, который передает каждый вызов нескольким логгерам
+type teeLogger []ILogger
+
+// Tee возвращает логгер, который передает каждый вызов всем loggers по порядку
+// и останавливает их все в Stop. место вызова в записях указывает на код,
+// вызвавший Tee, а не на сам Tee.
+// Fatal и Panic сначала пишут запись во все логгеры и сбрасывают их буферы,
+// и только потом завершают программу или паникуют
+func Tee(loggers ...ILogger) ILogger {
+	switch len(loggers) {
+	case 0:
+		return Nop()
+	case 1:
+		return loggers[0]
+	}
+
+	tee := make(teeLogger, len(loggers))
+	for i, l := range loggers {
+		tee[i] = AddCallerSkip(l, 1)
+	}
+
+	return tee
+}
+
+// логгер, который умеет писать запись заданного уровня без завершения программы.
+// нужен Tee, чтобы Fatal и Panic первого логгера не оборвали запись в остальные
+type levelLogger interface {
+	logLevel(level string, msg string, err error, params []string)
+}
+
+func (l *logger) logLevel(level string, msg string, err error, params []string) {
+	l.log(nil, level, msg, err, params, nil)
+}
+
+func (t teeLogger) logLevel(level string, msg string, err error, params []string) {
+	for _, l := range t {
+		if leveled, ok := l.(levelLogger); ok {
+			leveled.logLevel(level, msg, err, params)
+		} else {
+			l.Error(msg, err, params...)
+		}
+	}
+}
+
+func (t teeLogger) withCallerSkip(skip int) ILogger {
+	tee := make(teeLogger, len(t))
+	for i, l := range t {
+		tee[i] = AddCallerSkip(l, skip)
+	}
+
+	return tee
+}
+
+func (t teeLogger) Info(msg string, err error, params ...string) {
+	for _, l := range t {
+		l.Info(msg, err, params...)
+	}
+}
+
+func (t teeLogger) Debug(msg string, err error, params ...string) {
+	for _, l := range t {
+		l.Debug(msg, err, params...)
+	}
+}
+
+func (t teeLogger) Error(msg string, err error, params ...string) {
+	for _, l := range t {
+		l.Error(msg, err, params...)
+	}
+}
+
+func (t teeLogger) Infow(msg string, err error, fields ...Field) {
+	for _, l := range t {
+		l.Infow(msg, err, fields...)
+	}
+}
+
+func (t teeLogger) Debugw(msg string, err error, fields ...Field) {
+	for _, l := range t {
+		l.Debugw(msg, err, fields...)
+	}
+}
+
+func (t teeLogger) Errorw(msg string, err error, fields ...Field) {
+	for _, l := range t {
+		l.Errorw(msg, err, fields...)
+	}
+}
+
+func (t teeLogger) Fatal(msg string, err error, params ...string) {
+	//цикл не вынесен в функцию, чтобы глубина стека совпадала с остальными методами
+	for _, l := range t {
+		if leveled, ok := l.(levelLogger); ok {
+			leveled.logLevel(FatalLevel, msg, err, params)
+		} else {
+			l.Error(msg, err, params...)
+		}
+	}
+	t.Flush(context.Background())
+	exit(1)
+}
+
+func (t teeLogger) Panic(msg string, err error, params ...string) {
+	//цикл не вынесен в функцию, чтобы глубина стека совпадала с остальными методами
+	for _, l := range t {
+		if leveled, ok := l.(levelLogger); ok {
+			leveled.logLevel(PanicLevel, msg, err, params)
+		} else {
+			l.Error(msg, err, params...)
+		}
+	}
+	t.Flush(context.Background())
+	panic(msg)
+}
+
+func (t teeLogger) Infof(format string, args ...interface{}) {
+	for _, l := range t {
+		l.Infof(format, args...)
+	}
+}
+
+func (t teeLogger) Debugf(format string, args ...interface{}) {
+	for _, l := range t {
+		l.Debugf(format, args...)
+	}
+}
+
+func (t teeLogger) Errorf(format string, args ...interface{}) {
+	for _, l := range t {
+		l.Errorf(format, args...)
+	}
+}
+
+func (t teeLogger) InfoFn(msg func() string, err error, params ...string) {
+	msg = onceString(msg)
+	for _, l := range t {
+		l.InfoFn(msg, err, params...)
+	}
+}
+
+func (t teeLogger) DebugFn(msg func() string, err error, params ...string) {
+	msg = onceString(msg)
+	for _, l := range t {
+		l.DebugFn(msg, err, params...)
+	}
+}
+
+func (t teeLogger) ErrorFn(msg func() string, err error, params ...string) {
+	msg = onceString(msg)
+	for _, l := range t {
+		l.ErrorFn(msg, err, params...)
+	}
+}
+
+func (t teeLogger) InfoCtx(ctx context.Context, msg string, err error, params ...string) {
+	for _, l := range t {
+		l.InfoCtx(ctx, msg, err, params...)
+	}
+}
+
+func (t teeLogger) DebugCtx(ctx context.Context, msg string, err error, params ...string) {
+	for _, l := range t {
+		l.DebugCtx(ctx, msg, err, params...)
+	}
+}
+
+func (t teeLogger) ErrorCtx(ctx context.Context, msg string, err error, params ...string) {
+	for _, l := range t {
+		l.ErrorCtx(ctx, msg, err, params...)
+	}
+}
+
+func (t teeLogger) With(fields ...Field) ILogger {
+	tee := make(teeLogger, len(t))
+	for i, l := range t {
+		tee[i] = l.With(fields...)
+	}
+
+	return tee
+}
+
+func (t teeLogger) WithContext(ctx context.Context) ILogger {
+	tee := make(teeLogger, len(t))
+	for i, l := range t {
+		tee[i] = l.WithContext(ctx)
+	}
+
+	return tee
+}
+
+func (t teeLogger) Flush(ctx context.Context) error {
+	var errs []error
+	for i, l := range t {
+		if err := l.Flush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("логгер %d: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (t teeLogger) Sync() error {
+	var errs []error
+	for i, l := range t {
+		if err := l.Sync(); err != nil {
+			errs = append(errs, fmt.Errorf("логгер %d: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (t teeLogger) Stop() {
+	for _, l := range t {
+		l.Stop()
+	}
+}
+
+// сообщение вычисляется один раз, сколько бы логгеров его ни запросило
+func onceString(fn func() string) func() string {
+	var (
+		once sync.Once
+		msg  string
+	)
+
+	return func() string {
+		once.Do(func() { msg = fn() })
+		return msg
+	}
+}
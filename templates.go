@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// данные, которые доступны в пользовательском шаблоне
+// пример шаблона: {{.Time}} [{{.Level}}] {{.Message}} {{.Params}} {{.Error}}
+type templateData struct {
+	Time    string    //дата в формате "02.01.2006 15:04:05"
+	TimeUTC int64     //таймштамп в секундах
+	T       time.Time //время записи, для собственного форматирования через formatTime
+	Level   string
+	Message string
+	Params  string //параметры через запятую
+	Error   string
+	Caller  string //файл и строка вызова, если включен AddCaller
+	Func    string //функция, из которой был вызван логгер
+	Stack   string //стек вызовов с отступами, если включен StackTrace
+}
+
+// функции-помощники, доступные в шаблонах
+var templateFuncs = template.FuncMap{
+	//раскрашивает строку в цвет уровня: {{color .Level .Level}}
+	"color": func(level string, s string) string {
+		return levelColor(level) + s + noColor
+	},
+	//дополняет строку пробелами справа до нужной ширины: {{pad 5 .Level}}
+	"pad": func(width int, s string) string {
+		if n := width - len([]rune(s)); n > 0 {
+			return s + strings.Repeat(" ", n)
+		}
+		return s
+	},
+	//дополняет строку пробелами слева до нужной ширины: {{padLeft 5 .Level}}
+	"padLeft": func(width int, s string) string {
+		if n := width - len([]rune(s)); n > 0 {
+			return strings.Repeat(" ", n) + s
+		}
+		return s
+	},
+	//форматирует время по шаблону пакета time: {{formatTime "15:04:05" .T}}
+	"formatTime": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	"upper": strings.ToUpper,
+}
+
+// компилирует шаблон из конфига. пустая строка означает формат по умолчанию
+func compileTemplate(name string, text string) *template.Template {
+	if text == "" {
+		return nil
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		log.Fatal("Не удалось разобрать шаблон "+name+" ", err)
+	}
+
+	//неизвестные поля вроде {{.Msg}} видны только при выполнении,
+	//поэтому шаблон пробно применяется к образцу записи
+	sample := &recordType{Level: InfoLevel, Message: "sample"}
+	sample.setTime(time.Now())
+
+	if err := tmpl.Execute(io.Discard, newTemplateData(sample)); err != nil {
+		log.Fatal("Не удалось применить шаблон "+name+" ", err)
+	}
+
+	return tmpl
+}
+
+func newTemplateData(record *recordType) *templateData {
+	data := &templateData{
+		Time:    record.Date,
+		TimeUTC: record.TimeUTC,
+		T:       record.time,
+		Level:   record.Level,
+		Message: record.Message,
+		Params:  strings.Join(record.paramStrings(), ", "),
+	}
+
+	if record.Error != nil {
+		data.Error = record.Error.Message
+	}
+
+	if record.Caller != nil {
+		data.Caller = record.Caller.String()
+		data.Func = record.Caller.Function
+	}
+
+	data.Stack = stackString(record.Stack)
+
+	return data
+}
+
+// применяет шаблон к записи. при ошибке сообщает в stderr и возвращает false,
+// тогда запись выводится в формате по умолчанию, а не теряется вместе с программой
+func executeTemplate(tmpl *template.Template, record *recordType) (string, bool) {
+	var sb strings.Builder
+
+	err := tmpl.Execute(&sb, newTemplateData(record))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Не удалось применить шаблон "+tmpl.Name()+" ", err)
+		return "", false
+	}
+
+	return sb.String(), true
+}
@@ -1,10 +1,71 @@
 package logger
 
+import (
+	"fmt"
+	"time"
+)
+
 type recordType struct {
-	TimeUTC int64    `json:"timeUTC"`
-	Date    string   `json:"date"`
-	Level   string   `json:"level"`
-	Message string   `json:"message"`
-	Params  []string `json:"params, omitempty"`
-	Error   *string  `json:"error, omitempty"`
+	TimeUTC int64       `json:"timeUTC"`
+	Date    string      `json:"date,omitempty"`
+	Level   string      `json:"level"`
+	Message string      `json:"message"`
+	Params  []string    `json:"params,omitempty"` //строковые параметры вида key=value
+	Fields  fieldList   `json:"fields,omitempty"` //типизированные параметры
+	Error   *errorInfo  `json:"error,omitempty"`
+	Caller  *frameType  `json:"caller,omitempty"`
+	Stack   []frameType `json:"stack,omitempty"`
+
+	time    time.Time
+	encoded []byte     //строка записи в формате файла, см. encodeRecord
+	seq     uint64     //номер в журнале упреждающей записи, 0 если журнал выключен
+	written chan error //если не nil, вызывающий ждет записи на диск, см. FlushOnWait
+}
+
+// проставляет время записи. нулевое время оставляет запись без даты
+func (r *recordType) setTime(t time.Time) {
+	r.time = t
+
+	if t.IsZero() {
+		r.TimeUTC = 0
+		r.Date = ""
+		return
+	}
+
+	r.TimeUTC = t.Unix()
+	r.Date = t.Format("02.01.2006 15:04:05")
+}
+
+// сообщает ждущему вызывающему, что запись дошла до диска или не дойдет никогда
+func (r *recordType) notifyWritten(err error) {
+	if r.written != nil {
+		r.written <- err
+	}
+}
+
+// все параметры записи в виде строк key=value
+func (r *recordType) paramStrings() []string {
+	list := make([]string, 0, len(r.Params)+len(r.Fields))
+	list = append(list, r.Params...)
+
+	for _, f := range r.Fields {
+		list = append(list, f.String())
+	}
+
+	return list
+}
+
+// ShutdownError остановка не успела сохранить все записи до завершения контекста
+type ShutdownError struct {
+	Unsaved int64  //сколько записей еще не записано в файлы. горутины уровней продолжают их сохранять
+	Dropped uint64 //сколько записей отброшено из-за переполнения каналов и после остановки, см. Stats
+	Err     error
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("logger: остановка прервана, не сохранено записей: %d, отброшено записей: %d: %v", e.Unsaved, e.Dropped, e.Err)
+}
+
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
 }
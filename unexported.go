@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -9,13 +10,18 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-func (l *logger) listenChan(level string) {
-	defer l.wg.Done()
+func (l *logger) listenChan(ctx context.Context, wg *sync.WaitGroup, level string) {
+	defer wg.Done()
 	ch := l.getChan(level)
+	flushCh := l.getFlushChan(level)
+	overflow := l.getOverflow(level)
 	logs := make([]*recordType, 0, l.bufferCapacity)
+	batchBytes := 0 //размер пачки в байтах, считается только если задан MaxBatchBytes
 
 	//добавление логов в файл происходит пачками равными размеру массива logs
 	//экспериментальным путем выяснил, что эффективнее всего иметь размер такой пачки примерно 10-20 логов
@@ -23,36 +29,93 @@ func (l *logger) listenChan(level string) {
 	//если в пачке более 20 логов, например 1000, то получается слишком большой кусок данных,
 	//который долго проходит этапы подготовки и долго записывается
 
-	after := time.After(time.Second * time.Duration(int(l.writeTimout)))
+	//один таймер на все время работы горутины. он взводится, когда в пустую пачку
+	//приходит первая запись, и останавливается, когда пачка записана. так запись
+	//ждет в пачке не дольше flushInterval, а простаивающая горутина не просыпается
+	timer := newTimer(l.clock)
+	defer timer.Stop()
+
+	//пишет пачку в файл и останавливает таймер
+	writeLogs := func(reason string) error {
+		if len(logs) == 0 {
+			return nil
+		}
+
+		l.debug(fmt.Sprintf("%sсохраняю логги канала %s: %s%s", red, level, reason, noColor))
+		timer.Stop()
+
+		err := l.write(level, logs)
+		logs = make([]*recordType, 0, l.bufferCapacity)
+		batchBytes = 0
+
+		return err
+	}
+
+	//добавляет запись в пачку. возвращает true, если пачку пора писать
+	addLog := func(log *recordType) bool {
+		if len(logs) == 0 {
+			timer.Reset(l.flushInterval)
+		}
+
+		logs = append(logs, log)
+
+		//одна запись может весить десятки килобайт, поэтому пачка ограничивается и по размеру
+		if l.maxBatchBytes > 0 {
+			batchBytes += len(l.encodeRecord(log))
+		}
+
+		//важные записи не ждут заполнения пачки
+		return len(logs) >= l.bufferCapacity ||
+			(l.maxBatchBytes > 0 && batchBytes >= l.maxBatchBytes) ||
+			l.flushImmediately(log)
+	}
 
 	for {
 		select {
-		//сценарий сохранения логов после сигнала остановки
-		case <-l.ctx.Done():
+		//сценарий сохранения логов после сигнала остановки или перезапуска горутин
+		case <-ctx.Done():
 			l.debug(fmt.Sprintf("%sзапускаю сохранение перед остановкой %s. количество несохраненных логов в канале %v%s", darkGreen, level, len(ch), noColor))
-			l.saveBeforeExit(ch, level, logs)
+			l.saveBeforeExit(ch, level, append(logs, overflow.takeSpill()...))
 			l.debug(fmt.Sprintf("%sзавершил сохранение перед остановкой, перестал слушать канал %s%s", darkBlue, level, noColor))
 			return
 
 		//сценарий когда долго не поступало логов и буфер logs полупустой
-		case <-after:
-			if len(logs) > 0 {
-				l.debug(fmt.Sprintf("%sсохраняю логги из полупустого слайса канала %s%s", darkPurple, level, noColor))
-				l.write(level, logs)
-				logs = make([]*recordType, 0, l.bufferCapacity)
-			}
-			//перезапускаю таймер
-			after = time.After(time.Second * time.Duration(int(l.writeTimout)))
+		case <-timer.c:
+			writeLogs("истек таймаут полупустого слайса")
 
-		//обычный сценарий который срабатывает при заполненности буфера
+		//обычный сценарий, пачка пишется сразу, как только заполнилась
 		case log := <-ch:
-			if len(logs) == l.bufferCapacity {
-				l.debug(fmt.Sprintf("%sсохраняю логги из слайса канала %s%s", red, level, noColor))
-				l.write(level, logs)
-				logs = make([]*recordType, 0, l.bufferCapacity)
+			if addLog(log) == true {
+				writeLogs("пачка заполнена")
+			}
+
+		//сценарий когда канал переполнялся и записи ушли в очередь переполнения
+		case <-overflow.spillSignal:
+			full := false
+			for _, log := range overflow.takeSpill() {
+				full = addLog(log) || full
+			}
+
+			if full == true {
+				writeLogs("из очереди переполнения")
 			}
 
-			logs = append(logs, log)
+		//сценарий принудительного сброса: пишу слайс и все, что уже лежит в канале
+		case req := <-flushCh:
+			for len(ch) > 0 {
+				addLog(<-ch)
+			}
+			for _, log := range overflow.takeSpill() {
+				addLog(log)
+			}
+
+			err := writeLogs("сброс по запросу")
+
+			if req.sync == true && err == nil {
+				err = l.syncFile(level)
+			}
+
+			req.done <- err
 			//default:
 		}
 	}
@@ -103,34 +166,79 @@ func (l *logger) saveFromChannel(ch chan *recordType, level string) {
 	}
 }
 
-func getFileName(level string) string {
-	y, m, d := time.Now().Date()
+func (l *logger) getFileName(level string) string {
+	return getFileNameAt(level, l.clock.Now())
+}
+
+// имя файла уровня за день, в который попадает t
+func getFileNameAt(level string, t time.Time) string {
+	y, m, d := t.Date()
 	return level + "_logs_" + strconv.Itoa(d) + "_" + m.String() + "_" + strconv.Itoa(y) + ".log"
 }
 
-func (l *logger) write(level string, recordList []*recordType) {
+func (l *logger) write(level string, recordList []*recordType) error {
+	defer atomic.AddInt64(&l.pending, -int64(len(recordList)))
+
 	var (
-		fileName    = getFileName(level)
+		fileName    = l.getFileName(level)
 		msgByte     = l.prepareRecordByte(recordList)
 		directories = path.Join(l.pathFolder, level)
-		pathFile    = path.Join(directories, fileName)
 	)
 
+	//fsync, если кто-то из вызывающих ждет записи на диск или включен журнал:
+	//журнал обрезается после подтверждения, и без fsync записи могли бы остаться
+	//только в кеше страниц и пропасть при отключении питания
+	needSync := l.durable
+	for _, r := range recordList {
+		needSync = needSync || r.written != nil
+	}
+
+	err := appendFile(directories, fileName, msgByte, needSync)
+	if err == nil {
+		//записи дошли до файла, журнал упреждающей записи их больше не хранит
+		l.getWAL(level).ack(recordList)
+	}
+
+	for _, r := range recordList {
+		r.notifyWritten(err)
+	}
+
+	return err
+}
+
+// дописывает данные в файл, при необходимости создавая директории
+func appendFile(directories string, fileName string, msgByte []byte, sync bool) error {
+	//при ошибках не завершаю программу, иначе пропадут логи из буферов остальных горутин.
+	//пачка, которую не удалось записать, выводится в stderr
 	err := os.MkdirAll(directories, 0777)
 	if err != nil {
-		log.Fatal("Создать директории не удалось ", err)
+		writeFailed("Создать директории не удалось ", err, msgByte)
+		return err
 	}
 
-	file, err := os.OpenFile(pathFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0777)
+	file, err := os.OpenFile(path.Join(directories, fileName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0777)
 	if err != nil {
-		log.Fatal("Открыть файл не удалась ", err)
+		writeFailed("Открыть файл не удалась ", err, msgByte)
+		return err
 	}
 	defer file.Close()
 
 	_, err = file.Write(msgByte)
 	if err != nil {
-		log.Fatal("Запись файла не удалась ", err)
+		writeFailed("Запись файла не удалась ", err, msgByte)
+		return err
 	}
+
+	if sync == true {
+		return file.Sync()
+	}
+
+	return nil
+}
+
+func writeFailed(msg string, err error, msgByte []byte) {
+	fmt.Fprintln(os.Stderr, msg, err)
+	os.Stderr.Write(msgByte)
 }
 
 // подготавливает список логов к записи
@@ -138,14 +246,31 @@ func (l *logger) prepareRecordByte(recordList []*recordType) []byte {
 
 	sortedRecordList := sortLogs(recordList)
 
-	if l.format == JSONFormat {
-		return l.prepareJSON(sortedRecordList)
+	var msgByte []byte
+	for _, r := range sortedRecordList {
+		msgByte = append(msgByte, l.encodeRecord(r)...)
 	}
 
-	return l.prepareString(sortedRecordList)
+	return msgByte
 
 }
 
+// готовит строку записи в формате файла. результат запоминается в записи,
+// чтобы не готовить ее повторно, например после записи в журнал упреждающей записи
+func (l *logger) encodeRecord(r *recordType) []byte {
+	if r.encoded != nil {
+		return r.encoded
+	}
+
+	if l.format == JSONFormat {
+		r.encoded = l.prepareJSON([]*recordType{r})
+	} else {
+		r.encoded = l.prepareString([]*recordType{r})
+	}
+
+	return r.encoded
+}
+
 func (l *logger) prepareJSON(recordList []*recordType) []byte {
 	var list []string
 
@@ -165,17 +290,32 @@ func (l *logger) prepareString(recordList []*recordType) []byte {
 	var list []string
 
 	for _, r := range recordList {
+		if l.fileTemplate != nil {
+			if line, ok := executeTemplate(l.fileTemplate, r); ok == true {
+				list = append(list, strings.TrimSuffix(line, "\n")+"\n")
+				continue
+			}
+		}
+
 		recordString :=
 			"Level: " + r.Level +
 				", Date: " + r.Date +
 				", Message: " + r.Message
 
-		if len(r.Params) != 0 {
-			recordString += ", Params: " + strings.Join(r.Params, ", ")
+		if params := r.paramStrings(); len(params) != 0 {
+			recordString += ", Params: " + strings.Join(params, ", ")
 		}
 
 		if r.Error != nil {
-			recordString += ", Error: " + *r.Error
+			recordString += ", Error: " + r.Error.Message
+		}
+
+		if r.Caller != nil {
+			recordString += ", Caller: " + r.Caller.String() + " " + r.Caller.Function
+		}
+
+		if len(r.Stack) != 0 {
+			recordString += ", Stack:" + stackString(r.Stack)
 		}
 
 		list = append(list, recordString+"\n")
@@ -202,28 +342,144 @@ func checkOccupancyChan(logChan chan recordType, limit int) bool {
 	return false
 }
 
-func (l *logger) collectRecord(level string, msg string, err error, params ...string) *recordType {
-	now := time.Now()
-	date := now.Format("02.01.2006 15:04:05")
+// собирает запись и отправляет ее в консоль и в канал уровня
+func (l *logger) log(ctx context.Context, level string, msg string, err error, params []string, fields []Field) {
+	if l.enabled(level) == false {
+		return
+	}
+
+	if ctxFields := l.contextFields(ctx); len(ctxFields) != 0 {
+		fields = append(ctxFields, fields...)
+	}
+
+	record := l.collectRecord(level, msg, err, fields, params...)
+
+	if l.addCaller == true {
+		record.Caller = getCaller(logCallerSkip + l.baseCallerSkip + l.callerSkip)
+	}
+
+	if l.stackTrace == true {
+		record.Stack = l.getStack(level, err, logCallerSkip+l.baseCallerSkip+l.callerSkip)
+	}
+
+	l.send(record)
+}
+
+// печатает готовую запись в консоль и отправляет ее в канал уровня
+func (l *logger) send(record *recordType) {
+	if l.hook != nil {
+		l.hook(newEntry(record))
+	}
+
+	if l.isPrint(record.Level) == true {
+		fmt.Fprintln(l.output, l.prepareToPrint(record))
+	}
+
+	if l.isWrite(record.Level) == false {
+		return
+	}
+
+	if l.flushOnWait == true && l.flushImmediately(record) == true {
+		record.written = make(chan error, 1)
+	}
+
+	l.mu.RLock()
+
+	//после остановки каналы никто не читает, отправка в них повесила бы вызывающего
+	if l.closed == true {
+		l.mu.RUnlock()
+		l.sendAfterStop(record)
+		return
+	}
+
+	//уровень могли выключить перезагрузкой конфига, пока вызывающий ждал блокировку
+	if l.isWrite(record.Level) == false {
+		l.mu.RUnlock()
+		return
+	}
+
+	l.getWAL(record.Level).append(l, record)
+
+	l.enqueue(l.getChan(record.Level), record)
+
+	l.mu.RUnlock()
+
+	//жду записи на диск без блокировки, иначе остановка логгера ждала бы этого вызывающего
+	if record.written != nil {
+		<-record.written
+	}
+}
+
+// запись уровня FlushOn и выше пишется в файл сразу, не дожидаясь заполнения пачки
+func (l *logger) flushImmediately(record *recordType) bool {
+	return l.flushOn != "" && levelRank(record.Level) >= levelRank(l.flushOn)
+}
+
+// порядок уровней по важности
+func levelRank(level string) int {
+	switch level {
+	case DebugLevel:
+		return 1
+	case InfoLevel:
+		return 2
+	case ErrorLevel:
+		return 3
+	case PanicLevel:
+		return 4
+	case FatalLevel:
+		return 5
+	default:
+		return 0
+	}
+}
+
+func (l *logger) sendAfterStop(record *recordType) {
+	if l.afterStop == AfterStopDrop {
+		atomic.AddUint64(&l.dropped, 1)
+		return
+	}
 
+	os.Stderr.Write(l.prepareRecordByte([]*recordType{record}))
+}
+
+func (l *logger) collectRecord(level string, msg string, err error, fields []Field, params ...string) *recordType {
 	record := &recordType{
-		TimeUTC: now.Unix(),
 		Level:   level,
-		Date:    date,
 		Message: msg,
 		Params:  params,
+		Fields:  resolveFields(mergeFields(l.fields, fields)),
 	}
+	record.setTime(l.clock.Now())
 
 	if err != nil {
-		errStr := err.Error()
-		record.Error = &errStr
+		record.Error = newErrorInfo(err)
 	}
 
 	return record
 }
 
+// стек ошибки, если она его несет, иначе стек вызова для уровня error
+func (l *logger) getStack(level string, err error, skip int) []frameType {
+	if pcs := errorStack(err); pcs != nil {
+		return framesFromPCs(pcs, l.stackFrameLimit)
+	}
+
+	//fatal и panic важнее error, стек нужен и им
+	if levelRank(level) >= levelRank(ErrorLevel) {
+		return captureStack(skip+1, l.stackFrameLimit)
+	}
+
+	return nil
+}
+
 func (l *logger) prepareToPrint(record *recordType) string {
-	if l.color == true {
+	if l.consoleTemplate != nil {
+		if line, ok := executeTemplate(l.consoleTemplate, record); ok == true {
+			return line
+		}
+	}
+
+	if l.color.Load() == true {
 		return makeMessageColorful(record)
 	}
 
@@ -233,42 +489,62 @@ func (l *logger) prepareToPrint(record *recordType) string {
 			"\nMessage: " + record.Message
 
 	if record.Error != nil {
-		recordString += "\nError: " + *record.Error
+		recordString += "\nError: " + record.Error.Message
+	}
+
+	if record.Caller != nil {
+		recordString += "\nCaller: " + record.Caller.String() + " " + record.Caller.Function
+	}
+
+	if len(record.Stack) != 0 {
+		recordString += "\nStack:" + stackString(record.Stack)
 	}
 
 	return recordString
 }
 
-func makeMessageColorful(record *recordType) string {
-	var color string
-
-	switch record.Level {
-	case Info:
-		color = darkGreen
-	case Debug:
-		color = blue
-	case Error:
-		color = red
+// цвет уровня для вывода в консоль
+func levelColor(level string) string {
+	switch level {
+	case InfoLevel:
+		return darkGreen
+	case DebugLevel:
+		return blue
+	case ErrorLevel, FatalLevel, PanicLevel:
+		return red
 	//case Query:
-	//	return orange + level + ": " + noColor + msg
+	//	return orange
 	//case Critical:
-	//	return darkBlue + level + ": " + noColor + msg
+	//	return darkBlue
 	//case Warning:
-	//	return darkPurple + level + ": " + noColor + msg
+	//	return darkPurple
 	default:
+		return noColor
 	}
+}
+
+func makeMessageColorful(record *recordType) string {
+	color := levelColor(record.Level)
 
 	recordString :=
 		"\nLevel: " + color + record.Level + noColor +
 			"\nDate: " + record.Date +
 			"\nMessage: " + record.Message
 
-	if len(record.Params) != 0 {
-		recordString += "\nParams: " + strings.Join(record.Params, ", ")
+	if params := record.paramStrings(); len(params) != 0 {
+		recordString += "\nParams: " + strings.Join(params, ", ")
 	}
 
 	if record.Error != nil {
-		recordString += "\nError: " + *record.Error
+		recordString += "\nError: " + record.Error.Message
+	}
+
+	if record.Caller != nil {
+		recordString += "\nCaller: " + record.Caller.String() + " " + record.Caller.Function
+	}
+
+	if len(record.Stack) != 0 {
+		recordString += "\nStack:" + stackString(record.Stack)
 	}
 
 	return recordString
@@ -276,11 +552,11 @@ func makeMessageColorful(record *recordType) string {
 
 func (l *logger) getChan(level string) chan *recordType {
 	switch level {
-	case Info:
+	case InfoLevel:
 		return l.infoChan
-	case Debug:
+	case DebugLevel:
 		return l.debugChan
-	case Error:
+	case ErrorLevel, FatalLevel, PanicLevel:
 		return l.errorChan
 	//case Query:
 	//	return l.queryChan
@@ -294,6 +570,76 @@ func (l *logger) getChan(level string) chan *recordType {
 	}
 }
 
+func (l *logger) getOverflow(level string) *overflowState {
+	switch level {
+	case InfoLevel:
+		return l.infoOverflow
+	case DebugLevel:
+		return l.debugOverflow
+	case ErrorLevel, FatalLevel, PanicLevel:
+		return l.errorOverflow
+	default:
+		return nil
+	}
+}
+
+func (l *logger) getWAL(level string) *walType {
+	switch level {
+	case InfoLevel:
+		return l.infoWAL
+	case DebugLevel:
+		return l.debugWAL
+	case ErrorLevel, FatalLevel, PanicLevel:
+		return l.errorWAL
+	default:
+		return nil
+	}
+}
+
+func (l *logger) getFlushChan(level string) chan flushRequest {
+	switch level {
+	case InfoLevel:
+		return l.infoFlush
+	case DebugLevel:
+		return l.debugFlush
+	case ErrorLevel, FatalLevel, PanicLevel:
+		return l.errorFlush
+	default:
+		return nil
+	}
+}
+
+// уровень включен, если записи уровня печатаются в консоль или пишутся в файл
+func (l *logger) enabled(level string) bool {
+	return l.hook != nil || l.isPrint(level) || l.isWrite(level)
+}
+
+func (l *logger) isPrint(level string) bool {
+	switch level {
+	case InfoLevel:
+		return l.printInfo.Load()
+	case DebugLevel:
+		return l.printDebug.Load()
+	case ErrorLevel, FatalLevel, PanicLevel:
+		return l.printError.Load()
+	default:
+		return false
+	}
+}
+
+func (l *logger) isWrite(level string) bool {
+	switch level {
+	case InfoLevel:
+		return l.writeInfo.Load()
+	case DebugLevel:
+		return l.writeDebug.Load()
+	case ErrorLevel, FatalLevel, PanicLevel:
+		return l.writeError.Load()
+	default:
+		return false
+	}
+}
+
 func (l *logger) debug(msg string) {
 	if l.debugLog == true {
 		fmt.Println("Дебагер логгера: ", msg)
@@ -1,14 +1,25 @@
 package logger
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/slogtest"
 	"time"
+
+	"github.com/IlyaKharitonov/logger/clocktest"
 )
 
 //func TestSortLogs(t *testing.T) {
@@ -48,14 +59,14 @@ import (
 
 // тест логгера на корректную запись и сортировку поступающих логгов
 // выясняю не пропадают ли логги при большой нагрузке (40 воркеров, 100000 логов от каждого в каждый канал)
-// перед запуском теста не должно быть логов rm -R logs
 // обратил внимание что значение лимита (Limit) оптимально в пределах 10-20
 // TODO понять как влияет размер буфера в каналах на производительость
 // TODO понять как влияет размер слайса в горутине (Limit)
 func TestLogger(t *testing.T) {
 
+	folder := t.TempDir()
 	config := &LoggerConf{
-		PathFolder: "./logs",
+		PathFolder: folder,
 
 		PrintError: false,
 		WriteError: true,
@@ -69,6 +80,8 @@ func TestLogger(t *testing.T) {
 		ChanCapacity:   100,
 		Color:          true,
 		DebugLog:       true,
+		//фиксированная дата, чтобы имя файла не зависело от дня запуска
+		Clock: clocktest.NewClock(time.Date(2024, time.March, 15, 12, 0, 0, 0, time.Local)),
 	}
 
 	logger := New(config)
@@ -88,14 +101,13 @@ func TestLogger(t *testing.T) {
 
 	logger.Stop()
 
-	y, m, d := time.Now().Date()
-	partOfName := "_logs_" + strconv.Itoa(d) + "_" + m.String() + "_" + strconv.Itoa(y) + ".log"
+	partOfName := "_logs_15_March_2024.log"
 
 	//проверяю количество строк в логе, оно должно быть
 	//равно количество горутин на количество циклов внутри горутины
 	/////////////////////////////////////////////////////////////////////////////
 
-	dataInfo, _ := ioutil.ReadFile("logs/info/info" + partOfName)
+	dataInfo, _ := ioutil.ReadFile(filepath.Join(folder, InfoLevel, InfoLevel+partOfName))
 	c := strings.Count(string(dataInfo), "\n")
 
 	if c != numWorkers*numCircles {
@@ -106,7 +118,7 @@ func TestLogger(t *testing.T) {
 
 	/////////////////////////////////////////////////////////////////////////////
 
-	dataError, _ := ioutil.ReadFile("logs/error/error" + partOfName)
+	dataError, _ := ioutil.ReadFile(filepath.Join(folder, ErrorLevel, ErrorLevel+partOfName))
 	c = strings.Count(string(dataError), "\n")
 
 	if c != numWorkers*numCircles {
@@ -117,7 +129,7 @@ func TestLogger(t *testing.T) {
 
 	/////////////////////////////////////////////////////////////////////////////
 
-	dataDebug, _ := ioutil.ReadFile("logs/debug/debug" + partOfName)
+	dataDebug, _ := ioutil.ReadFile(filepath.Join(folder, DebugLevel, DebugLevel+partOfName))
 	c = strings.Count(string(dataDebug), "\n")
 
 	if c != numWorkers*numCircles {
@@ -161,3 +173,1266 @@ func workerImitation(num int, logger *logger, ctx context.Context, numCircles in
 //default:
 //	//logger.Info(nil, "closing program by default")
 //}
+
+// тест пользовательских шаблонов для файла и консоли
+func TestTemplates(t *testing.T) {
+	logger := New(&LoggerConf{
+		Format:          "text",
+		BufferCapacity:  1,
+		ChanCapacity:    1,
+		FileTemplate:    "{{.Time}} [{{.Level}}] {{.Message}} {{.Params}} {{.Error}}",
+		ConsoleTemplate: `{{formatTime "2006" .T}} {{pad 6 .Level}}|{{padLeft 3 "x"}} {{color .Level .Message}}`,
+	})
+
+	record := logger.collectRecord(ErrorLevel, "сообщение", errors.New("ошибка"), nil, logger.AddParam("a", 1))
+
+	fileLine := string(logger.prepareString([]*recordType{record}))
+	expected := record.Date + " [error] сообщение a=1 ошибка\n"
+	if fileLine != expected {
+		t.Errorf("строка в файле %q не равна ожидаемой %q", fileLine, expected)
+	}
+
+	consoleLine := logger.prepareToPrint(record)
+	expected = strconv.Itoa(record.time.Year()) + " error |  x " + red + "сообщение" + noColor
+	if consoleLine != expected {
+		t.Errorf("строка в консоли %q не равна ожидаемой %q", consoleLine, expected)
+	}
+
+	//шаблон падает только на записях с ошибкой: такая запись выводится в формате по умолчанию
+	fallback := New(&LoggerConf{
+		ConsoleTemplate: "{{if .Error}}{{.Error.Text}}{{end}}{{.Message}}",
+	})
+	if line := fallback.prepareToPrint(record); !strings.Contains(line, "Message: сообщение") {
+		t.Errorf("запись не выведена в формате по умолчанию: %q", line)
+	}
+}
+
+// тест типизированных полей в json
+func TestFieldsJSON(t *testing.T) {
+	logger := New(&LoggerConf{Format: "json", BufferCapacity: 1, ChanCapacity: 1})
+
+	fields := []Field{
+		String("s", "v"),
+		Int("i", 42),
+		Int64("i64", 1<<40),
+		Float("f", 1.5),
+		Bool("b", true),
+		Duration("d", time.Second),
+		Time("t", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)),
+		Err("e", errors.New("ошибка")),
+		Any("a", []int{1, 2}),
+	}
+	record := logger.collectRecord(InfoLevel, "сообщение", nil, fields, "old=param", logger.AddParam("n", 7))
+
+	//старые вызовы со срезом строк компилируются, как и раньше
+	params := []string{"old=param", logger.AddParam("n", 7)}
+	logger.Info("сообщение", nil, params...)
+
+	data := string(logger.prepareJSON([]*recordType{record}))
+
+	expected := `"params":["old=param","n=7"],"fields":{"s":"v","i":42,"i64":1099511627776,"f":1.5,"b":true,"d":1000000000,` +
+		`"t":"2024-01-02T03:04:05Z","e":"ошибка","a":[1,2]}`
+	if !strings.Contains(data, expected) {
+		t.Errorf("json %s не содержит %s", data, expected)
+	}
+}
+
+// тест дочерних логгеров с привязанными полями
+func TestWith(t *testing.T) {
+	root := New(&LoggerConf{Format: "json", BufferCapacity: 1, ChanCapacity: 1})
+
+	child := root.With(String("request_id", "1"), Int("n", 1)).(*logger)
+	grandchild := child.With(Int("n", 2), Bool("b", true)).(*logger)
+
+	record := grandchild.collectRecord(InfoLevel, "сообщение", nil, []Field{String("call", "x")})
+
+	expected := `"fields":{"request_id":"1","n":2,"b":true,"call":"x"}`
+	if data := string(root.prepareJSON([]*recordType{record})); !strings.Contains(data, expected) {
+		t.Errorf("json %s не содержит %s", data, expected)
+	}
+
+	if len(child.fields) != 2 || child.core != root.core {
+		t.Errorf("дочерний логгер изменил поля родителя или не разделяет с ним ядро")
+	}
+
+	//изменение среза после With не меняет поля дочернего логгера
+	fields := []Field{String("request_id", "1")}
+	bound := root.With(fields...).(*logger)
+	fields[0] = String("request_id", "2")
+	if bound.fields[0].Value != "1" {
+		t.Errorf("поля дочернего логгера изменились вместе со срезом вызывающего: %v", bound.fields)
+	}
+}
+
+// тест извлечения полей из контекста
+func TestContextFields(t *testing.T) {
+	custom := New(&LoggerConf{
+		Format:            "json",
+		BufferCapacity:    1,
+		ChanCapacity:      1,
+		ContextExtractors: append(DefaultContextExtractors, ExtractValue("tenant", "tenant-key")),
+	})
+
+	ctx := ContextWithTraceID(context.Background(), "trace-1")
+	ctx = ContextWithRequestID(ctx, "req-1")
+	ctx = context.WithValue(ctx, "tenant-key", 7)
+
+	child := custom.WithContext(ctx).(*logger)
+	record := child.collectRecord(InfoLevel, "сообщение", nil, nil)
+
+	expected := `"fields":{"trace_id":"trace-1","request_id":"req-1","tenant":7}`
+	if data := string(custom.prepareJSON([]*recordType{record})); !strings.Contains(data, expected) {
+		t.Errorf("json %s не содержит %s", data, expected)
+	}
+}
+
+// прогон slog.Handler через testing/slogtest. записи пишутся в файлы,
+// а затем разбираются обратно в map с ключами time, level, msg и вложенными группами
+func TestSlogHandler(t *testing.T) {
+	var (
+		current *logger
+		folder  string
+	)
+
+	newHandler := func(t *testing.T) slog.Handler {
+		folder = t.TempDir()
+		current = New(&LoggerConf{
+			PathFolder:     folder,
+			WriteInfo:      true,
+			WriteDebug:     true,
+			WriteError:     true,
+			Format:         "json",
+			BufferCapacity: 15,
+			ChanCapacity:   100,
+			WriteTimout:    1,
+		})
+		return current.Handler()
+	}
+
+	result := func(t *testing.T) map[string]any {
+		current.Stop()
+
+		files, _ := filepath.Glob(filepath.Join(folder, "*", "*.log"))
+		if len(files) != 1 {
+			t.Fatalf("ожидался один файл с логами, найдено %d", len(files))
+		}
+
+		data, err := os.ReadFile(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var record struct {
+			Date    string         `json:"date"`
+			Level   string         `json:"level"`
+			Message string         `json:"message"`
+			Fields  map[string]any `json:"fields"`
+		}
+		if err := json.Unmarshal(data, &record); err != nil {
+			t.Fatal(err)
+		}
+
+		m := map[string]any{slog.LevelKey: record.Level, slog.MessageKey: record.Message}
+		if record.Date != "" {
+			m[slog.TimeKey] = record.Date
+		}
+
+		//разворачиваю ключи вида "group.key" обратно во вложенные map
+		for key, value := range record.Fields {
+			parts := strings.Split(key, ".")
+			group := m
+			for _, part := range parts[:len(parts)-1] {
+				next, ok := group[part].(map[string]any)
+				if !ok {
+					next = map[string]any{}
+					group[part] = next
+				}
+				group = next
+			}
+			group[parts[len(parts)-1]] = value
+		}
+
+		return m
+	}
+
+	slogtest.Run(t, newHandler, result)
+}
+
+// тест перехвата стандартного log и io.Writer
+func TestWriter(t *testing.T) {
+	folder := t.TempDir()
+	logger := New(&LoggerConf{
+		PathFolder:     folder,
+		WriteInfo:      true,
+		Format:         "text",
+		BufferCapacity: 15,
+		ChanCapacity:   100,
+		WriteTimout:    1,
+	})
+
+	logger.StdLogger(InfoLevel).Printf("из стандартного логгера %d", 1)
+
+	w := logger.Writer(InfoLevel)
+	w.Write([]byte("первая\nвто"))
+	w.Write([]byte("рая\r\n\nтретья"))
+	w.Close()
+
+	logger.Stop()
+
+	data, _ := os.ReadFile(filepath.Join(folder, InfoLevel, logger.getFileName(InfoLevel)))
+	for _, msg := range []string{"из стандартного логгера 1", "первая", "вторая", "третья"} {
+		if !strings.Contains(string(data), "Message: "+msg+"\n") {
+			t.Errorf("в файле нет строки %q:\n%s", msg, data)
+		}
+	}
+
+	if c := strings.Count(string(data), "\n"); c != 4 {
+		t.Errorf("количество строк в файле %d не равно 4", c)
+	}
+}
+
+// тест ленивых методов: для выключенного уровня сообщение и поля не вычисляются
+func TestLazy(t *testing.T) {
+	folder := t.TempDir()
+	logger := New(&LoggerConf{
+		PathFolder:     folder,
+		WriteInfo:      true,
+		Format:         "json",
+		BufferCapacity: 15,
+		ChanCapacity:   100,
+		WriteTimout:    1,
+	})
+
+	calls := 0
+	expensive := func() interface{} {
+		calls++
+		return calls
+	}
+
+	lazy := logger.With(Lazy("n", expensive))
+	lazy.DebugFn(func() string { calls++; return "дебаг" }, nil)
+	lazy.Debugw("дебаг", nil, Lazy("m", expensive))
+	logger.Debugf("%v", Lazy("n", expensive))
+	if calls != 0 {
+		t.Errorf("для выключенного уровня debug были вызваны функции: %d", calls)
+	}
+
+	lazy.InfoFn(func() string { return "инфо" }, nil)
+	logger.Infof("число %d", 5)
+	logger.Stop()
+
+	data, _ := os.ReadFile(filepath.Join(folder, InfoLevel, logger.getFileName(InfoLevel)))
+	if !strings.Contains(string(data), `"message":"инфо","fields":{"n":1}`) ||
+		!strings.Contains(string(data), `"message":"число 5"`) {
+		t.Errorf("в файле нет ожидаемых записей:\n%s", data)
+	}
+}
+
+// тест определения места вызова для разных методов и оберток
+func TestCaller(t *testing.T) {
+	folder := t.TempDir()
+	logger := New(&LoggerConf{
+		PathFolder:     folder,
+		WriteInfo:      true,
+		Format:         "json",
+		BufferCapacity: 15,
+		ChanCapacity:   100,
+		WriteTimout:    1,
+		AddCaller:      true,
+	})
+
+	wrapper := AddCallerSkip(logger.With(String("child", "1")), 1)
+	logWrapped := func(msg string) {
+		wrapper.Info(msg, nil)
+	}
+
+	logger.Info("info", nil)
+	logger.Infof("infof")
+	logger.InfoCtx(context.Background(), "ctx", nil)
+	logWrapped("wrapped")
+	logger.StdLogger(InfoLevel).Print("std")
+	slog.New(logger.Handler()).Info("slog")
+
+	logger.Stop()
+
+	data, _ := os.ReadFile(filepath.Join(folder, InfoLevel, logger.getFileName(InfoLevel)))
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("в файле %d строк вместо 6:\n%s", len(lines), data)
+	}
+
+	for _, line := range lines {
+		var record struct {
+			Message string    `json:"message"`
+			Caller  frameType `json:"caller"`
+		}
+		json.Unmarshal([]byte(line), &record)
+
+		if filepath.Base(record.Caller.File) != "unit_test.go" || !strings.HasSuffix(record.Caller.Function, ".TestCaller") {
+			t.Errorf("запись %q: неверное место вызова %+v", record.Message, record.Caller)
+		}
+	}
+}
+
+// ошибка со своим стеком, как в github.com/pkg/errors
+type stackError struct {
+	pcs []uintptr
+}
+
+func (e *stackError) Error() string { return "ошибка со стеком" }
+
+func (e *stackError) StackTrace() []uintptr { return e.pcs }
+
+func newStackError() error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(1, pcs)
+	return &stackError{pcs: pcs[:n]}
+}
+
+// тест стека вызовов для уровня error и для ошибок со своим стеком
+func TestStack(t *testing.T) {
+	logger := New(&LoggerConf{
+		Format:          "json",
+		BufferCapacity:  1,
+		ChanCapacity:    1,
+		StackTrace:      true,
+		StackFrameLimit: 2,
+	})
+
+	stack := logger.getStack(ErrorLevel, errors.New("без стека"), 0)
+	if len(stack) != 2 || !strings.HasSuffix(stack[0].Function, ".TestStack") {
+		t.Errorf("неверный стек для уровня error: %+v", stack)
+	}
+
+	for _, level := range []string{FatalLevel, PanicLevel} {
+		if stack := logger.getStack(level, nil, 0); len(stack) == 0 {
+			t.Errorf("нет стека для уровня %s", level)
+		}
+	}
+
+	stack = logger.getStack(InfoLevel, fmt.Errorf("обертка: %w", newStackError()), 0)
+	if len(stack) != 2 || !strings.HasSuffix(stack[0].Function, ".newStackError") ||
+		!strings.HasSuffix(stack[1].Function, ".TestStack") {
+		t.Errorf("неверный стек из ошибки: %+v", stack)
+	}
+
+	if stack := logger.getStack(InfoLevel, nil, 0); stack != nil {
+		t.Errorf("для уровня info без ошибки не должно быть стека: %+v", stack)
+	}
+
+	expected := "\n\t" + stack[0].Function + "\n\t\t" + stack[0].File + ":" + strconv.Itoa(stack[0].Line)
+	if s := stackString(stack[:1]); s != expected {
+		t.Errorf("текстовый стек %q не равен ожидаемому %q", s, expected)
+	}
+}
+
+// ошибка, которая добавляет свои поля в запись
+type statusError struct {
+	status int
+}
+
+func (e *statusError) Error() string { return "статус " + strconv.Itoa(e.status) }
+
+func (e *statusError) LogFields() []Field { return []Field{Int("status", e.status)} }
+
+// тест разбора цепочек обернутых и объединенных ошибок
+func TestErrorChain(t *testing.T) {
+	logger := New(&LoggerConf{Format: "json", BufferCapacity: 1, ChanCapacity: 1})
+
+	inner := fmt.Errorf("запрос: %w", &statusError{status: 404})
+	err := fmt.Errorf("обработчик: %w", errors.Join(inner, errors.New("вторая")))
+
+	record := logger.collectRecord(ErrorLevel, "сообщение", err, nil)
+	data := string(logger.prepareJSON([]*recordType{record}))
+
+	expected := `"error":{"message":"обработчик: запрос: статус 404\nвторая","type":"*fmt.wrapError",` +
+		`"chain":[{"message":"запрос: статус 404\nвторая","type":"*errors.joinError"}],` +
+		`"causes":[{"message":"запрос: статус 404","type":"*fmt.wrapError",` +
+		`"chain":[{"message":"статус 404","type":"*logger.statusError"}],"fields":{"status":404}},` +
+		`{"message":"вторая","type":"*errors.errorString"}]}`
+	if !strings.Contains(data, expected) {
+		t.Errorf("json %s не содержит %s", data, expected)
+	}
+}
+
+// тест Fatal и Panic: записи всех уровней должны оказаться в файлах до выхода или паники
+func TestFatalAndPanic(t *testing.T) {
+	folder := t.TempDir()
+	logger := New(&LoggerConf{
+		PathFolder:     folder,
+		WriteInfo:      true,
+		WriteError:     true,
+		Format:         "json",
+		BufferCapacity: 15,
+		ChanCapacity:   100,
+		WriteTimout:    60,
+	})
+	defer logger.Stop()
+
+	code := 0
+	exit = func(c int) { code = c }
+	defer func() { exit = os.Exit }()
+
+	logger.Info("перед fatal", nil)
+	logger.Fatal("fatal", errors.New("ошибка"))
+
+	if code != 1 {
+		t.Errorf("Fatal не вызвал выход с кодом 1")
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != "panic" {
+				t.Errorf("Panic не вызвал панику с сообщением: %v", r)
+			}
+		}()
+		logger.Panic("panic", nil)
+	}()
+
+	info, _ := os.ReadFile(filepath.Join(folder, InfoLevel, logger.getFileName(InfoLevel)))
+	errs, _ := os.ReadFile(filepath.Join(folder, ErrorLevel, logger.getFileName(ErrorLevel)))
+
+	if !strings.Contains(string(info), `"message":"перед fatal"`) ||
+		!strings.Contains(string(errs), `"level":"fatal","message":"fatal"`) ||
+		!strings.Contains(string(errs), `"level":"panic","message":"panic"`) {
+		t.Errorf("записи не сброшены в файлы:\n%s\n%s", info, errs)
+	}
+}
+
+func panicking() {
+	panic("что-то сломалось")
+}
+
+// тест перехвата паники: запись со стеком сбрасывается в файл, паника гасится или повторяется
+func TestRecoverAndLog(t *testing.T) {
+	folder := t.TempDir()
+	logger := New(&LoggerConf{
+		PathFolder:     folder,
+		WriteError:     true,
+		Format:         "json",
+		BufferCapacity: 15,
+		ChanCapacity:   100,
+		WriteTimout:    60,
+	})
+	defer logger.Stop()
+
+	func() {
+		defer logger.RecoverAndLog(RecoverOptions{})
+		panicking()
+	}()
+
+	func() {
+		defer func() {
+			if r := recover(); r != "что-то сломалось" {
+				t.Errorf("паника не была повторена: %v", r)
+			}
+		}()
+		defer logger.RecoverAndLog(RecoverOptions{Repanic: true, Message: "повтор"})
+		panicking()
+	}()
+
+	//неизвестный уровень пишется как error, а не теряется
+	func() {
+		defer logger.RecoverAndLog(RecoverOptions{Level: "critical"})
+		panicking()
+	}()
+
+	//паника в горутине из Go записывается и сбрасывается в файл, процесс продолжает работу
+	file := filepath.Join(folder, ErrorLevel, logger.getFileName(ErrorLevel))
+	logger.Go(panicking)
+
+	var lines []string
+	deadline := time.Now().Add(2 * time.Second)
+	for len(lines) != 4 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		data, _ := os.ReadFile(file)
+		lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+	if len(lines) != 4 {
+		t.Fatalf("в файле %d строк вместо 4:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+
+	for _, line := range lines {
+		var record struct {
+			Stack  []frameType       `json:"stack"`
+			Fields map[string]string `json:"fields"`
+		}
+		json.Unmarshal([]byte(line), &record)
+
+		if record.Fields["panic"] != "что-то сломалось" || len(record.Stack) == 0 ||
+			!strings.HasSuffix(record.Stack[0].Function, ".panicking") {
+			t.Errorf("неверная запись о панике: %s", line)
+		}
+	}
+}
+
+// тест Flush и Sync: записи попадают в файлы без остановки логгера
+func TestFlushAndSync(t *testing.T) {
+	folder := t.TempDir()
+	logger := New(&LoggerConf{
+		PathFolder:     folder,
+		WriteInfo:      true,
+		WriteDebug:     true,
+		Format:         "json",
+		BufferCapacity: 15,
+		ChanCapacity:   100,
+		WriteTimout:    60,
+	})
+	defer logger.Stop()
+
+	count := func(level string) int {
+		data, _ := os.ReadFile(filepath.Join(folder, level, logger.getFileName(level)))
+		return strings.Count(string(data), "\n")
+	}
+
+	for i := 0; i < 20; i++ {
+		logger.Info("инфо", nil)
+	}
+	logger.Debug("дебаг", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := logger.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if count(InfoLevel) != 20 || count(DebugLevel) != 1 {
+		t.Errorf("после Flush в файлах %d и %d строк вместо 20 и 1", count(InfoLevel), count(DebugLevel))
+	}
+
+	logger.Info("инфо", nil)
+	if err := logger.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	if count(InfoLevel) != 21 {
+		t.Errorf("после Sync в файле %d строк вместо 21", count(InfoLevel))
+	}
+}
+
+// тест остановки: повторный Stop не блокируется, запись после остановки не вешает вызывающего
+func TestShutdown(t *testing.T) {
+	logger := New(&LoggerConf{
+		PathFolder:     t.TempDir(),
+		WriteInfo:      true,
+		Format:         "json",
+		BufferCapacity: 1,
+		ChanCapacity:   1,
+		WriteTimout:    60,
+		AfterStop:      AfterStopDrop,
+	})
+
+	logger.Info("до остановки", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := logger.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	logger.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			logger.Info("после остановки", nil)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("запись после остановки заблокировала вызывающего")
+	}
+
+	if logger.Dropped() != 10 {
+		t.Errorf("отброшено %d записей вместо 10", logger.Dropped())
+	}
+
+	//вызывающий застрял в отправке в канал и держит RLock: Shutdown все равно соблюдает срок
+	stuck := New(&LoggerConf{
+		PathFolder:     t.TempDir(),
+		WriteInfo:      true,
+		Format:         "json",
+		BufferCapacity: 1,
+		ChanCapacity:   1,
+	})
+	stuck.mu.RLock()
+	atomic.AddUint64(&stuck.getOverflow(InfoLevel).dropped, 2)
+
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			results <- stuck.Shutdown(ctx)
+		}()
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-results:
+			var shutdownErr *ShutdownError
+			if !errors.As(err, &shutdownErr) {
+				t.Errorf("Shutdown вернул %v вместо ShutdownError", err)
+			} else if shutdownErr.Dropped != 2 {
+				t.Errorf("отброшено %d записей вместо 2", shutdownErr.Dropped)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Shutdown не соблюдает срок ctx")
+		}
+	}
+
+	stuck.mu.RUnlock()
+	if err := stuck.Shutdown(context.Background()); err != nil {
+		t.Errorf("остановка после освобождения блокировки: %v", err)
+	}
+
+	//логгер без записи в файлы не держит горутину до остановки
+	before := runtime.NumGoroutine()
+	printers := make([]interface{ Shutdown(context.Context) error }, 100)
+	for i := range printers {
+		printers[i] = New(&LoggerConf{PrintInfo: true, Output: ioutil.Discard})
+	}
+	if leaked := runtime.NumGoroutine() - before; leaked >= len(printers) {
+		t.Errorf("логгеры без записи в файлы запустили %d горутин", leaked)
+	}
+	for _, printer := range printers {
+		if err := printer.Shutdown(ctx); err != nil {
+			t.Errorf("остановка логгера без записи в файлы: %v", err)
+		}
+	}
+}
+
+// тест политик переполнения канала. горутины не запущены, канал заполняется напрямую
+func TestOverflowPolicies(t *testing.T) {
+	clock := clocktest.NewClock(time.Now())
+	logger := New(&LoggerConf{
+		Format:          "json",
+		BufferCapacity:  1,
+		ChanCapacity:    1,
+		OverflowInfo:    OverflowDropOldest,
+		OverflowDebug:   OverflowDropNewest,
+		OverflowError:   OverflowBlockTimeout,
+		OverflowTimeout: time.Minute,
+		Clock:           clock,
+	})
+
+	enqueue := func(ch chan *recordType, level string, msg string) {
+		logger.enqueue(ch, &recordType{Level: level, Message: msg})
+	}
+
+	ch := make(chan *recordType, 1)
+	enqueue(ch, DebugLevel, "первая")
+	enqueue(ch, DebugLevel, "вторая")
+	if r := <-ch; r.Message != "первая" {
+		t.Errorf("drop_newest оставил в канале %q", r.Message)
+	}
+
+	enqueue(ch, InfoLevel, "первая")
+	enqueue(ch, InfoLevel, "вторая")
+	if r := <-ch; r.Message != "вторая" {
+		t.Errorf("drop_oldest оставил в канале %q", r.Message)
+	}
+
+	//отправка ждет места в канале, пока часы не переведены на OverflowTimeout
+	enqueue(ch, ErrorLevel, "первая")
+	done := make(chan struct{})
+	go func() {
+		enqueue(ch, ErrorLevel, "вторая")
+		close(done)
+	}()
+
+	if !clock.BlockUntil(1, time.Second) {
+		t.Fatal("таймер ожидания места в канале не взведен")
+	}
+	clock.Advance(time.Minute - time.Second)
+	select {
+	case <-done:
+		t.Fatal("timeout отбросил запись раньше срока")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timeout не отбросил запись по истечении срока")
+	}
+	if r := <-ch; r.Message != "первая" {
+		t.Errorf("timeout оставил в канале %q", r.Message)
+	}
+
+	spill := New(&LoggerConf{Format: "json", BufferCapacity: 1, ChanCapacity: 1, OverflowInfo: OverflowSpill})
+	spill.enqueue(ch, &recordType{Level: InfoLevel, Message: "первая"})
+	spill.enqueue(ch, &recordType{Level: InfoLevel, Message: "вторая"})
+	if list := spill.infoOverflow.takeSpill(); len(list) != 1 || list[0].Message != "вторая" || len(ch) != 1 {
+		t.Errorf("spill не положил запись в очередь переполнения")
+	}
+
+	stats := logger.Stats()
+	if stats.Dropped[InfoLevel] != 1 || stats.Dropped[DebugLevel] != 1 || stats.Dropped[ErrorLevel] != 1 || spill.Stats().Spilled[InfoLevel] != 1 {
+		t.Errorf("неверные счетчики: %+v %+v", stats, spill.Stats())
+	}
+}
+
+// тест журнала упреждающей записи: записи, не дошедшие до файла, восстанавливаются при следующем New
+func TestDurable(t *testing.T) {
+	folder := t.TempDir()
+	config := &LoggerConf{
+		PathFolder:     folder,
+		WriteInfo:      true,
+		Format:         "json",
+		BufferCapacity: 15,
+		ChanCapacity:   100,
+		WriteTimout:    3600,
+		Durable:        true,
+	}
+
+	count := func(name string) int {
+		data, _ := os.ReadFile(name)
+		return strings.Count(string(data), "\n")
+	}
+	logFile := filepath.Join(folder, InfoLevel, getFileNameAt(InfoLevel, time.Now()))
+	walFile := walPath(folder, InfoLevel)
+
+	//записи подтверждаются после записи в файл, после чего журнал обрезается
+	logger := New(config)
+	logger.Info("первая", nil)
+	if count(walFile) != 1 {
+		t.Fatalf("в журнале %d строк вместо 1", count(walFile))
+	}
+	logger.Flush(context.Background())
+	if count(walFile) != 0 || count(logFile) != 1 {
+		t.Fatalf("после Flush в журнале %d строк, в файле %d", count(walFile), count(logFile))
+	}
+	logger.Stop()
+
+	//имитация падения: записи остались в буфере горутины и в журнале
+	crashed := New(config)
+	for i := 0; i < 3; i++ {
+		crashed.Info("до падения", nil)
+	}
+	if count(logFile) != 1 {
+		t.Fatalf("записи попали в файл раньше времени")
+	}
+
+	restarted := New(config)
+	defer restarted.Stop()
+
+	if c := count(logFile); c != 4 {
+		t.Errorf("после восстановления в файле %d строк вместо 4", c)
+	}
+}
+
+// ждет, пока в файле не окажется want строк, или истечет секунда
+func waitLines(name string, want int) int {
+	deadline := time.Now().Add(time.Second)
+
+	for {
+		data, _ := os.ReadFile(name)
+		c := strings.Count(string(data), "\n")
+		if c >= want || time.Now().After(deadline) {
+			return c
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// тест записи по короткому интервалу и по размеру пачки в байтах
+func TestFlushTriggers(t *testing.T) {
+	folder := t.TempDir()
+	byInterval := New(&LoggerConf{
+		PathFolder:     folder,
+		WriteInfo:      true,
+		Format:         "json",
+		BufferCapacity: 1000,
+		ChanCapacity:   100,
+		FlushInterval:  20 * time.Millisecond,
+	})
+	defer byInterval.Stop()
+
+	byInterval.Info("по интервалу", nil)
+	if c := waitLines(filepath.Join(folder, InfoLevel, byInterval.getFileName(InfoLevel)), 1); c != 1 {
+		t.Errorf("по интервалу записано %d строк вместо 1", c)
+	}
+
+	folder = t.TempDir()
+	bySize := New(&LoggerConf{
+		PathFolder:     folder,
+		WriteInfo:      true,
+		Format:         "json",
+		BufferCapacity: 1000,
+		ChanCapacity:   100,
+		FlushInterval:  time.Hour,
+		MaxBatchBytes:  200,
+	})
+	defer bySize.Stop()
+
+	bySize.Info(strings.Repeat("большая запись ", 10), nil)
+	bySize.Info("маленькая", nil)
+	if c := waitLines(filepath.Join(folder, InfoLevel, bySize.getFileName(InfoLevel)), 1); c != 1 {
+		t.Errorf("по размеру записано %d строк вместо 1", c)
+	}
+}
+
+// тест немедленной записи для важных уровней
+func TestFlushOn(t *testing.T) {
+	folder := t.TempDir()
+	logger := New(&LoggerConf{
+		PathFolder:     folder,
+		WriteInfo:      true,
+		WriteError:     true,
+		Format:         "json",
+		BufferCapacity: 1000,
+		ChanCapacity:   100,
+		FlushInterval:  time.Hour,
+		FlushOn:        ErrorLevel,
+		FlushOnWait:    true,
+	})
+	defer logger.Stop()
+
+	count := func(level string) int {
+		data, _ := os.ReadFile(filepath.Join(folder, level, logger.getFileName(level)))
+		return strings.Count(string(data), "\n")
+	}
+
+	logger.Info("инфо", nil)
+	logger.Error("ошибка", nil)
+
+	//вызов Error вернулся только после записи, поэтому ждать не нужно
+	if count(ErrorLevel) != 1 {
+		t.Errorf("запись уровня error не записана сразу")
+	}
+
+	if count(InfoLevel) != 0 {
+		t.Errorf("запись уровня info записана раньше заполнения пачки")
+	}
+}
+
+// тест таймера горутины на фейковых часах: запись по таймауту и сразу по заполнении пачки
+func TestListenChanTimer(t *testing.T) {
+	folder := t.TempDir()
+	clock := clocktest.NewClock(time.Now())
+	logger := New(&LoggerConf{
+		PathFolder:     folder,
+		WriteInfo:      true,
+		Format:         "json",
+		BufferCapacity: 3,
+		ChanCapacity:   100,
+		FlushInterval:  time.Minute,
+		Clock:          clock,
+	})
+	defer logger.Stop()
+
+	name := filepath.Join(folder, InfoLevel, logger.getFileName(InfoLevel))
+	count := func() int {
+		data, _ := os.ReadFile(name)
+		return strings.Count(string(data), "\n")
+	}
+
+	//пока записей нет, таймер не взведен
+	if !clock.BlockUntil(0, time.Second) {
+		t.Fatal("таймер взведен без записей")
+	}
+
+	logger.Info("первая", nil)
+	if !clock.BlockUntil(1, time.Second) {
+		t.Fatal("таймер не взвелся после первой записи")
+	}
+
+	clock.Advance(time.Minute - time.Second)
+	if count() != 0 {
+		t.Fatal("пачка записана раньше таймаута")
+	}
+
+	clock.Advance(time.Second)
+	if c := waitLines(name, 1); c != 1 {
+		t.Fatalf("по таймауту записано %d строк вместо 1", c)
+	}
+	if !clock.BlockUntil(0, time.Second) {
+		t.Fatal("таймер не остановлен после записи пачки")
+	}
+
+	//пачка пишется сразу после третьей записи, не дожидаясь четвертой
+	for i := 0; i < 3; i++ {
+		logger.Info("пачка", nil)
+	}
+	if c := waitLines(name, 4); c != 4 {
+		t.Fatalf("заполненная пачка не записана: строк %d вместо 4", c)
+	}
+	if !clock.BlockUntil(0, time.Second) {
+		t.Fatal("таймер не остановлен после записи заполненной пачки")
+	}
+}
+
+// тест смены файла в полночь и формата времени записи на фейковых часах
+func TestClockRollover(t *testing.T) {
+	folder := t.TempDir()
+	clock := clocktest.NewClock(time.Date(2024, time.December, 31, 23, 59, 30, 0, time.Local))
+	logger := New(&LoggerConf{
+		PathFolder:     folder,
+		WriteInfo:      true,
+		Format:         "json",
+		BufferCapacity: 1000,
+		ChanCapacity:   100,
+		FlushInterval:  time.Minute,
+		Clock:          clock,
+	})
+	defer logger.Stop()
+
+	logger.Info("до полуночи", nil)
+	if err := logger.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(time.Minute)
+	logger.Info("после полуночи", nil)
+	if err := logger.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) []recordType {
+		data, _ := os.ReadFile(filepath.Join(folder, InfoLevel, name))
+		var records []recordType
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var r recordType
+			if err := json.Unmarshal([]byte(line), &r); err == nil {
+				records = append(records, r)
+			}
+		}
+		return records
+	}
+
+	before := read("info_logs_31_December_2024.log")
+	if len(before) != 1 || before[0].Message != "до полуночи" || before[0].Date != "31.12.2024 23:59:30" {
+		t.Errorf("неверные записи до полуночи: %+v", before)
+	}
+
+	after := read("info_logs_1_January_2025.log")
+	if len(after) != 1 || after[0].Message != "после полуночи" || after[0].Date != "01.01.2025 00:00:30" {
+		t.Errorf("неверные записи после полуночи: %+v", after)
+	}
+
+	if len(before) == 1 && len(after) == 1 && after[0].TimeUTC-before[0].TimeUTC != 60 {
+		t.Errorf("разница таймштампов %d вместо 60", after[0].TimeUTC-before[0].TimeUTC)
+	}
+}
+
+// тест пустого логгера и логгера, передающего вызовы нескольким логгерам
+func TestNopAndTee(t *testing.T) {
+	code := 0
+	exit = func(c int) { code = c }
+	defer func() { exit = os.Exit }()
+
+	nop := Nop()
+	nop.With(String("k", "v")).Info("ничего", nil)
+	nop.Fatal("fatal", nil)
+	if code != 1 {
+		t.Errorf("Fatal пустого логгера не вызвал выход с кодом 1")
+	}
+
+	folder := t.TempDir()
+	file := New(&LoggerConf{
+		PathFolder:     folder,
+		WriteInfo:      true,
+		WriteError:     true,
+		Format:         "json",
+		BufferCapacity: 15,
+		ChanCapacity:   100,
+		WriteTimout:    60,
+	})
+
+	var (
+		mu      sync.Mutex
+		entries []Entry
+	)
+	//без записи в файлы формат и размеры буферов не нужны
+	observer := New(&LoggerConf{
+		AddCaller: true,
+		Hook: func(e Entry) {
+			mu.Lock()
+			entries = append(entries, e)
+			mu.Unlock()
+		},
+	})
+
+	tee := Tee(file, observer).With(String("request_id", "42"))
+	defer tee.Stop()
+
+	calls := 0
+	tee.InfoFn(func() string { calls++; return "лениво" }, nil)
+	if calls != 1 {
+		t.Errorf("сообщение вычислено %d раз вместо 1", calls)
+	}
+
+	code = 0
+	tee.Fatal("fatal", errors.New("ошибка"))
+	if code != 1 {
+		t.Errorf("Fatal не вызвал выход с кодом 1")
+	}
+
+	mu.Lock()
+	if len(entries) != 2 || entries[1].Level != FatalLevel || len(entries[1].Fields) != 1 {
+		t.Errorf("неверные записи наблюдателя: %+v", entries)
+	} else if !strings.Contains(entries[0].Caller, "unit_test.go") || !strings.Contains(entries[1].Caller, "unit_test.go") {
+		t.Errorf("место вызова указывает не на тест: %q, %q", entries[0].Caller, entries[1].Caller)
+	}
+	mu.Unlock()
+
+	info, _ := os.ReadFile(filepath.Join(folder, InfoLevel, file.getFileName(InfoLevel)))
+	errs, _ := os.ReadFile(filepath.Join(folder, ErrorLevel, file.getFileName(ErrorLevel)))
+	if !strings.Contains(string(info), `"message":"лениво"`) ||
+		!strings.Contains(string(errs), `"level":"fatal","message":"fatal"`) {
+		t.Errorf("записи не сброшены в файлы перед выходом:\n%s\n%s", info, errs)
+	}
+}
+
+// тест логгера по умолчанию и функций пакета
+func TestDefault(t *testing.T) {
+	previous := Default()
+	defer SetDefault(previous)
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		newStderrLogger()
+	}
+	if leaked := runtime.NumGoroutine() - before; leaked >= 100 {
+		t.Errorf("логгер по умолчанию запускает горутины: %d", leaked)
+	}
+
+	var buf bytes.Buffer
+	SetDefault(New(&LoggerConf{
+		PrintInfo: true,
+		AddCaller: true,
+		Output:    &buf,
+	}))
+
+	Info("через пакет", nil)
+	Debug("debug выключен", nil)
+
+	out := buf.String()
+	if !strings.Contains(out, "через пакет") || strings.Contains(out, "debug выключен") {
+		t.Errorf("неверный вывод логгера по умолчанию:\n%s", out)
+	}
+	if !strings.Contains(out, "unit_test.go") {
+		t.Errorf("место вызова указывает не на тест:\n%s", out)
+	}
+
+	//замена логгера одновременно с логированием
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				Error("ошибка", nil)
+				SetDefault(Nop())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// тест независимых логгеров, настроенных опциями
+func TestOptions(t *testing.T) {
+	auditFolder, appFolder := t.TempDir(), t.TempDir()
+
+	audit := New(WithPath(auditFolder), WithFormat(JSONFormat), WithWrite(InfoLevel), WithBuffers(15, 100))
+	defer audit.Stop()
+
+	var console bytes.Buffer
+	base := &LoggerConf{Format: TextFormat, BufferCapacity: 15, ChanCapacity: 100}
+	app := New(base, WithPath(appFolder), WithWrite(ErrorLevel), WithPrint(ErrorLevel), WithOutput(&console))
+	defer app.Stop()
+
+	if base.PathFolder != "" {
+		t.Errorf("опции изменили переданный конфиг")
+	}
+
+	audit.Info("аудит", nil)
+	app.Info("info выключен", nil)
+	app.Error("ошибка приложения", nil)
+
+	if err := audit.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	auditData, _ := os.ReadFile(filepath.Join(auditFolder, InfoLevel, audit.getFileName(InfoLevel)))
+	if !strings.Contains(string(auditData), `"message":"аудит"`) {
+		t.Errorf("нет записи аудита в json:\n%s", auditData)
+	}
+
+	appData, _ := os.ReadFile(filepath.Join(appFolder, ErrorLevel, app.getFileName(ErrorLevel)))
+	if !strings.Contains(string(appData), "ошибка приложения") || strings.HasPrefix(string(appData), "{") {
+		t.Errorf("нет записи приложения в текстовом формате:\n%s", appData)
+	}
+
+	if _, err := os.Stat(filepath.Join(appFolder, InfoLevel)); err == nil {
+		t.Errorf("логгер приложения записал выключенный уровень info")
+	}
+	if _, err := os.Stat(filepath.Join(auditFolder, ErrorLevel)); err == nil {
+		t.Errorf("логгеры пишут в общую папку")
+	}
+
+	if !strings.Contains(console.String(), "ошибка приложения") {
+		t.Errorf("ошибка не напечатана в консоль:\n%s", console.String())
+	}
+
+	//конкурентный доступ к устаревшему общему конфигу без гонки
+	wg := &sync.WaitGroup{}
+	configs := make([]*LoggerConf, 4)
+	for i := range configs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			configs[i] = GetConfig()
+		}(i)
+	}
+	wg.Wait()
+	for _, c := range configs {
+		if c != configs[0] {
+			t.Errorf("GetConfig вернул разные конфиги")
+		}
+	}
+}
+
+// тест перезагрузки конфига из файла на работающем логгере
+func TestWatchConfig(t *testing.T) {
+	dir := t.TempDir()
+	oldFolder, newFolder := filepath.Join(dir, "old"), filepath.Join(dir, "new")
+	path := filepath.Join(dir, "logger.yaml")
+	modTime := time.Now()
+
+	writeConfig := func(text string) {
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		//время изменения всегда новое, даже если файл переписан в ту же наносекунду
+		modTime = modTime.Add(time.Second)
+		os.Chtimes(path, modTime, modTime)
+	}
+
+	var (
+		mu       sync.Mutex
+		rejected []Entry
+	)
+	clock := clocktest.NewClock(time.Now())
+	logger := New(&LoggerConf{
+		Clock: clock,
+		Hook: func(e Entry) {
+			if e.Level == ErrorLevel {
+				mu.Lock()
+				rejected = append(rejected, e)
+				mu.Unlock()
+			}
+		},
+	})
+	defer logger.Stop()
+
+	//логгер создан без записи в файлы, поэтому размер каналов обязателен
+	writeConfig("WriteInfo: true\nFormat: json\nBufferCapacity: 1000\nWriteTimout: 3600\nPathFolder: " + oldFolder + "\n")
+	if err := logger.WatchConfig(path); err == nil {
+		t.Fatal("конфиг без ChanCapacity применен к логгеру без каналов")
+	}
+
+	writeConfig("WriteInfo: true\nFormat: json\nBufferCapacity: 1000\nChanCapacity: 100\nWriteTimout: 3600\nPathFolder: " + oldFolder + "\n")
+	if err := logger.WatchConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	if cap(logger.getChan(InfoLevel)) != 100 {
+		t.Errorf("размер канала %d вместо 100", cap(logger.getChan(InfoLevel)))
+	}
+
+	logger.Info("до перезагрузки", nil)
+	logger.Debug("debug выключен", nil)
+
+	//проверка файла идет по таймеру, поэтому часы переводятся, пока условие не выполнится
+	eventually := func(cond func() bool) bool {
+		deadline := time.Now().Add(2 * time.Second)
+		for cond() == false {
+			if time.Now().After(deadline) {
+				return false
+			}
+			clock.Advance(configPollInterval)
+			time.Sleep(time.Millisecond)
+		}
+		return true
+	}
+
+	writeConfig("WriteInfo: true\nWriteDebug: true\nFormat: text\nBufferCapacity: 1000\nWriteTimout: 3600\nPathFolder: " + newFolder + "\n")
+
+	//при перезапуске горутин накопленная пачка сохраняется в старую папку
+	oldFile := filepath.Join(oldFolder, InfoLevel, logger.getFileName(InfoLevel))
+	if !eventually(func() bool { data, _ := os.ReadFile(oldFile); return len(data) > 0 }) {
+		t.Fatal("пачка не сохранена при перезагрузке конфига")
+	}
+	if !eventually(func() bool { return logger.isWrite(DebugLevel) }) {
+		t.Fatal("конфиг не применен")
+	}
+
+	old, _ := os.ReadFile(oldFile)
+	if !strings.Contains(string(old), `"message":"до перезагрузки"`) {
+		t.Errorf("неверная запись в старой папке:\n%s", old)
+	}
+
+	//второй наблюдатель за тем же логгером не запускается
+	if err := logger.WatchConfig(path); err == nil {
+		t.Error("повторный WatchConfig не вернул ошибку")
+	}
+
+	//ключи, которых нет в файле, сохраняют текущие значения
+	writeConfig("WriteError: true\n")
+	if !eventually(func() bool { return logger.currentConfig().WriteError }) {
+		t.Fatal("конфиг из одного ключа не применен")
+	}
+	if current := logger.currentConfig(); current.WriteInfo == false || current.WriteDebug == false ||
+		current.PathFolder != newFolder || current.Format != TextFormat || current.WriteTimout != 3600 {
+		t.Errorf("конфиг из одного ключа сбросил остальные настройки: %+v", current)
+	}
+
+	//неверный конфиг отклоняется и попадает в лог, прежний конфиг остается
+	writeConfig("WriteInfo: true\nFormat: xml\nBufferCapacity: 1000\nPathFolder: " + oldFolder + "\n")
+	if !eventually(func() bool { mu.Lock(); defer mu.Unlock(); return len(rejected) > 0 }) {
+		t.Fatal("неверный конфиг не попал в лог")
+	}
+
+	//папка, в которую нельзя писать, отклоняется до остановки горутин
+	notFolder := filepath.Join(dir, "file")
+	os.WriteFile(notFolder, nil, 0644)
+	err := logger.applyConfig(&LoggerConf{WriteInfo: true, Format: "json", BufferCapacity: 1000, PathFolder: notFolder})
+	if err == nil || logger.pathFolder != newFolder {
+		t.Errorf("конфиг с недоступной папкой применен: %v", err)
+	}
+
+	logger.Info("после перезагрузки", nil)
+	logger.Debug("debug включен", nil)
+	if err := logger.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	info, _ := os.ReadFile(filepath.Join(newFolder, InfoLevel, logger.getFileName(InfoLevel)))
+	debug, _ := os.ReadFile(filepath.Join(newFolder, DebugLevel, logger.getFileName(DebugLevel)))
+	if !strings.Contains(string(info), "после перезагрузки") || strings.HasPrefix(string(info), "{") {
+		t.Errorf("нет текстовой записи info в новой папке:\n%s", info)
+	}
+	if !strings.Contains(string(debug), "debug включен") || strings.Contains(string(debug), "debug выключен") {
+		t.Errorf("неверные записи debug в новой папке:\n%s", debug)
+	}
+
+	//json читается по расширению файла, неизвестные ключи отклоняются
+	jsonPath := filepath.Join(dir, "logger.json")
+	os.WriteFile(jsonPath, []byte(`{"WriteError": true, "Format": "json", "BufferCapacity": 5}`), 0644)
+	if config, err := readConfigFile(jsonPath, LoggerConf{}); err != nil || config.WriteError != true || config.BufferCapacity != 5 {
+		t.Errorf("json конфиг прочитан неверно: %+v, %v", config, err)
+	}
+	os.WriteFile(jsonPath, []byte(`{"Formatt": "json"}`), 0644)
+	if _, err := readConfigFile(jsonPath, LoggerConf{}); err == nil {
+		t.Errorf("конфиг с неизвестным ключом не отклонен")
+	}
+}
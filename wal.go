@@ -0,0 +1,226 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// папка журналов упреждающей записи внутри PathFolder
+const walFolder = ".wal"
+
+// walType журнал упреждающей записи одного уровня (режим Durable).
+// каждая запись до отправки в канал дописывается в журнал с fsync,
+// после записи в файл логов в журнал дописывается подтверждение.
+// неподтвержденные записи восстанавливаются при следующем New.
+// когда все записи подтверждены, журнал обрезается
+type walType struct {
+	mu      sync.Mutex
+	file    *os.File
+	nextSeq uint64
+	unacked int
+}
+
+// строка журнала: либо запись, либо список подтверждений
+type walEntry struct {
+	Seq  uint64   `json:"seq,omitempty"`
+	Time int64    `json:"time,omitempty"` //время записи в наносекундах, по нему выбирается файл
+	Data string   `json:"data,omitempty"` //готовая строка для файла логов
+	Ack  []uint64 `json:"ack,omitempty"`
+}
+
+func walPath(pathFolder string, level string) string {
+	return path.Join(pathFolder, walFolder, level+".wal")
+}
+
+// открывает журналы уровней, которые пишутся в файлы. при ошибке уже открытые журналы закрываются
+func (l *logger) openWALs() error {
+	var err error
+
+	if l.writeInfo.Load() == true && err == nil {
+		l.infoWAL, err = openWAL(l.pathFolder, InfoLevel)
+	}
+
+	if l.writeDebug.Load() == true && err == nil {
+		l.debugWAL, err = openWAL(l.pathFolder, DebugLevel)
+	}
+
+	if l.writeError.Load() == true && err == nil {
+		l.errorWAL, err = openWAL(l.pathFolder, ErrorLevel)
+	}
+
+	if err != nil {
+		l.closeWALs()
+	}
+
+	return err
+}
+
+func (l *logger) closeWALs() {
+	l.infoWAL.close()
+	l.debugWAL.close()
+	l.errorWAL.close()
+
+	l.infoWAL, l.debugWAL, l.errorWAL = nil, nil, nil
+}
+
+// восстанавливает неподтвержденные записи уровня из журнала прошлого запуска и открывает новый журнал
+func openWAL(pathFolder string, level string) (*walType, error) {
+	walFile := walPath(pathFolder, level)
+
+	if err := replayWAL(pathFolder, level, walFile); err != nil {
+		return nil, fmt.Errorf("восстановить журнал %s не удалось: %w", walFile, err)
+	}
+
+	if err := os.MkdirAll(path.Dir(walFile), 0777); err != nil {
+		return nil, fmt.Errorf("создать директорию журнала не удалось: %w", err)
+	}
+
+	file, err := os.OpenFile(walFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("открыть журнал %s не удалось: %w", walFile, err)
+	}
+
+	return &walType{file: file, nextSeq: 1}, nil
+}
+
+func replayWAL(pathFolder string, level string, walFile string) error {
+	file, err := os.Open(walFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entries := map[uint64]walEntry{}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	for scanner.Scan() {
+		var entry walEntry
+		//последняя строка может быть оборвана на середине, такие строки пропускаю
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+
+		if entry.Seq != 0 {
+			entries[entry.Seq] = entry
+		}
+
+		for _, seq := range entry.Ack {
+			delete(entries, seq)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	seqs := make([]uint64, 0, len(entries))
+	for seq := range entries {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+
+	//записи разных дней попадают в файлы своих дней
+	for _, seq := range seqs {
+		entry := entries[seq]
+		fileName := getFileNameAt(level, time.Unix(0, entry.Time))
+
+		err := appendFile(path.Join(pathFolder, level), fileName, []byte(entry.Data), true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// дописывает запись в журнал и дожидается fsync
+func (w *walType) append(l *logger, record *recordType) {
+	if w == nil {
+		return
+	}
+
+	data := l.encodeRecord(record)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	record.seq = w.nextSeq
+	w.nextSeq++
+
+	line, _ := json.Marshal(walEntry{Seq: record.seq, Time: record.time.UnixNano(), Data: string(data)})
+
+	if err := w.writeLine(line); err != nil {
+		fmt.Fprintln(os.Stderr, "Запись в журнал не удалась ", err)
+		record.seq = 0
+		return
+	}
+
+	w.unacked++
+}
+
+// подтверждает записи, дошедшие до файла логов или отброшенные
+func (w *walType) ack(recordList []*recordType) {
+	if w == nil {
+		return
+	}
+
+	var seqs []uint64
+	for _, r := range recordList {
+		if r.seq != 0 {
+			seqs = append(seqs, r.seq)
+		}
+	}
+
+	if len(seqs) == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.unacked -= len(seqs)
+
+	//все записи дошли до файлов, журнал можно начать заново
+	if w.unacked == 0 {
+		if err := w.file.Truncate(0); err == nil {
+			w.nextSeq = 1
+			return
+		}
+	}
+
+	line, _ := json.Marshal(walEntry{Ack: seqs})
+
+	if err := w.writeLine(line); err != nil {
+		fmt.Fprintln(os.Stderr, "Запись в журнал не удалась ", err)
+	}
+}
+
+func (w *walType) writeLine(line []byte) error {
+	if _, err := w.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	return w.file.Sync()
+}
+
+func (w *walType) close() {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.file.Close()
+}
@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"strings"
+	"sync"
+)
+
+// levelWriter io.Writer, который превращает каждую строку входных данных в запись уровня level.
+// незавершенная строка копится до следующего перевода строки или до Close
+type levelWriter struct {
+	logger *logger
+	level  string
+	skip   int //сколько кадров стека между writeLine и пользовательским кодом
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// Writer возвращает io.WriteCloser для сторонних библиотек, которые пишут диагностику в io.Writer.
+// каждая строка попадает в канал уровня level и записывается в файл вместе с остальными логами.
+// Close записывает последнюю строку, если она не закончилась переводом строки
+func (l *logger) Writer(level string) io.WriteCloser {
+	if l.getChan(level) == nil {
+		log.Fatal("Неизвестный уровень логирования ", level)
+	}
+
+	//writeLine -> Write -> код пользователя
+	return &levelWriter{logger: l, level: level, skip: 2}
+}
+
+// StdLogger возвращает *log.Logger из стандартной библиотеки, который пишет через этот логгер
+func (l *logger) StdLogger(level string) *log.Logger {
+	w := l.Writer(level).(*levelWriter)
+	//writeLine -> Write -> (*log.Logger).output -> (*log.Logger).Printf -> код пользователя
+	w.skip = 4
+
+	return log.New(w, "", 0)
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := len(p)
+
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.buf = append(w.buf, p...)
+			break
+		}
+
+		w.buf = append(w.buf, p[:i]...)
+		w.writeLine()
+		p = p[i+1:]
+	}
+
+	return n, nil
+}
+
+func (w *levelWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.writeLine()
+
+	return nil
+}
+
+// отправляет накопленную строку в логгер, пустые строки пропускаются
+func (w *levelWriter) writeLine() {
+	line := strings.TrimSuffix(string(w.buf), "\r")
+	w.buf = w.buf[:0]
+
+	if line == "" {
+		return
+	}
+
+	record := w.logger.collectRecord(w.level, line, nil, nil)
+
+	if w.logger.addCaller == true {
+		record.Caller = getCaller(w.skip + w.logger.baseCallerSkip + w.logger.callerSkip)
+	}
+
+	w.logger.send(record)
+}